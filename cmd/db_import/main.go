@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/jojonas/db_nmap/internal"
 )
@@ -12,8 +14,22 @@ var log = internal.Logger
 var version string = "dev"
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s XMLFILE [XMLFILE...]\n", os.Args[0])
+	internal.RegisterFlags(flag.CommandLine, "")
+
+	flag.Usage = func() {
+		formats := make([]string, 0, len(internal.Parsers))
+		for _, parser := range internal.Parsers {
+			formats = append(formats, parser.Name())
+		}
+
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [OPTIONS] FILE [FILE...]\n\nSupported formats (detected automatically): %s\nFiles may be compressed with gzip, bzip2, xz or zstd.\n\nOptions:\n", os.Args[0], strings.Join(formats, ", "))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	filenames := flag.Args()
+	if len(filenames) < 1 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
@@ -21,26 +37,39 @@ func main() {
 
 	ctx := context.Background()
 
-	db, workspaceId, err := internal.Connect(ctx)
+	targets := make([]string, 0)
+	if internal.CreateMissingWorkspace {
+		targets = readTargets(filenames)
+	}
+
+	db, workspaceId, err := internal.Connect(ctx, targets)
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 
+	insertHost := internal.InsertHost
+	if internal.DryRun {
+		insertHost = internal.DryRunHost
+	}
+
 	hostCount := 0
 	serviceCount := 0
+	failedCount := 0
 
-	for _, filename := range os.Args[1:] {
+	for _, filename := range filenames {
 		file, err := os.Open(filename)
 		if err != nil {
 			log.Errorf("Opening %q: %v", filename, err)
+			failedCount += 1
 			continue
 		}
 
-		err = internal.ParseNmapXML(file, func(host internal.NmapHost) error {
-			n, err := internal.InsertHost(db, int(workspaceId), host)
+		err = internal.ParseScanResults(file, func(host internal.NmapHost) error {
+			n, err := insertHost(db, workspaceId, host)
 
 			if err != nil {
-				log.Warnf("Inserting host into DB: %v", err)
+				log.Errorf("Inserting host %s into DB: %v", host, err)
+				failedCount += 1
 				return nil
 			}
 
@@ -52,10 +81,46 @@ func main() {
 			return nil
 		})
 
+		file.Close()
+
 		if err != nil {
 			log.Errorf("Parsing %q: %v", filename, err)
+			failedCount += 1
+		}
+	}
+
+	log.Infof("Import stats: registered %d hosts with %d services, %d failures.", hostCount, serviceCount, failedCount)
+
+	if failedCount > 0 && internal.Strict {
+		os.Exit(1)
+	}
+}
+
+// readTargets collects the targets of all scans, as the boundary of a newly
+// created workspace.
+func readTargets(filenames []string) []string {
+	targets := make([]string, 0)
+
+	for _, filename := range filenames {
+		file, err := os.Open(filename)
+		if err != nil {
+			log.Errorf("Opening %q: %v", filename, err)
+			continue
+		}
+
+		args, err := internal.ReadNmapArgs(file)
+		file.Close()
+
+		if err != nil {
+			log.Warnf("Reading scan arguments from %q: %v", filename, err)
+			continue
+		}
+
+		fields := strings.Fields(args)
+		if len(fields) > 1 {
+			targets = append(targets, internal.NmapTargets(fields[1:])...)
 		}
 	}
 
-	log.Infof("Import stats: registered %d hosts with %d services.", hostCount, serviceCount)
+	return targets
 }
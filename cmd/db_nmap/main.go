@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -18,31 +19,45 @@ var binaryPath = "nmap"
 var version string = "dev"
 
 func main() {
-	if hasArgument(os.Args, "--help") || hasArgument(os.Args, "-h") {
-		usage()
+	flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ExitOnError)
+	internal.RegisterFlags(flags, wrapperFlagPrefix)
+	flags.Usage = func() { usage(flags) }
+
+	wrapperArgs, nmapArgs := splitArguments(flags, os.Args[1:])
+	flags.Parse(wrapperArgs)
+
+	if hasArgument(nmapArgs, "--help") || hasArgument(nmapArgs, "-h") {
+		usage(flags)
 	}
 
 	log.Infof("db_nmap %s starting...", version)
 
 	ctx := context.Background()
 
-	db, workspaceId, err := internal.Connect(ctx)
+	db, workspaceId, err := internal.Connect(ctx, internal.NmapTargets(nmapArgs))
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 
-	cmd := exec.CommandContext(ctx, binaryPath, os.Args[1:]...)
+	insertHost := internal.InsertHost
+	if internal.DryRun {
+		insertHost = internal.DryRunHost
+	}
+
+	cmd := exec.CommandContext(ctx, binaryPath, nmapArgs...)
 
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	hostCount := 0
 	serviceCount := 0
+	failedCount := 0
 	err = runNmap(cmd, func(host internal.NmapHost) error {
-		n, err := internal.InsertHost(db, int(workspaceId), host)
+		n, err := insertHost(db, workspaceId, host)
 
 		if err != nil {
-			log.Warnf("Inserting host into DB: %v", err)
+			log.Errorf("Inserting host %s into DB: %v", host, err)
+			failedCount += 1
 			return nil
 		}
 
@@ -54,31 +69,49 @@ func main() {
 		return nil
 	})
 
-	log.Infof("Wrapper stats: registered %d hosts with %d services.", hostCount, serviceCount)
-
 	if err != nil {
 		log.Errorf("%v", err)
+		failedCount += 1
 	}
 
-	os.Exit(cmd.ProcessState.ExitCode())
+	log.Infof("Wrapper stats: registered %d hosts with %d services, %d failures.", hostCount, serviceCount, failedCount)
+
+	exitCode := 1
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+
+	// keep Nmap's exit code if it failed itself
+	if exitCode == 0 && failedCount > 0 && internal.Strict {
+		exitCode = 1
+	}
+
+	os.Exit(exitCode)
 }
 
 //go:embed usage.txt
 var usageTemplate string
 
-func usage() {
+func usage(flags *flag.FlagSet) {
+	var flagDefaults strings.Builder
+	flags.SetOutput(&flagDefaults)
+	flags.PrintDefaults()
+	flags.SetOutput(nil)
+
 	vars := struct {
-		Name            string
-		ConnString      string
-		WorkspaceEnvVar string
-		TestedVersions  []string
-		Version         string
+		Name           string
+		ConnString     string
+		FlagPrefix     string
+		FlagDefaults   string
+		TestedVersions []string
+		Version        string
 	}{
-		Name:            filepath.Base(os.Args[0]),
-		ConnString:      internal.ConnString,
-		WorkspaceEnvVar: internal.WorkspaceEnvVar,
-		TestedVersions:  internal.TestedVersions,
-		Version:         version,
+		Name:           filepath.Base(os.Args[0]),
+		ConnString:     internal.RedactPasswords(internal.ConnString),
+		FlagPrefix:     wrapperFlagPrefix,
+		FlagDefaults:   flagDefaults.String(),
+		TestedVersions: internal.TestedVersions,
+		Version:        version,
 	}
 
 	tmpl := template.New("usage.txt")
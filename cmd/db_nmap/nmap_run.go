@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -12,6 +13,37 @@ import (
 	"github.com/jojonas/db_nmap/internal"
 )
 
+// wrapperFlagPrefix is the prefix of the options of db_nmap itself, which are
+// not passed to Nmap.
+const wrapperFlagPrefix = "db-"
+
+// splitArguments separates the options of the wrapper (e.g. "--db-workspace
+// NAME" or "--db-dry-run", with one or two dashes) from the arguments for
+// Nmap.
+func splitArguments(flags *flag.FlagSet, args []string) ([]string, []string) {
+	wrapperArgs := make([]string, 0)
+	nmapArgs := make([]string, 0)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		name := strings.TrimLeft(arg, "-")
+		if !strings.HasPrefix(arg, "-") || !strings.HasPrefix(name, wrapperFlagPrefix) {
+			nmapArgs = append(nmapArgs, arg)
+			continue
+		}
+
+		wrapperArgs = append(wrapperArgs, arg)
+
+		if !strings.Contains(name, "=") && !internal.IsBoolFlag(flags, name) && i+1 < len(args) {
+			i++
+			wrapperArgs = append(wrapperArgs, args[i])
+		}
+	}
+
+	return wrapperArgs, nmapArgs
+}
+
 func ensureArgument(args []string, arguments ...string) []string {
 	searchArg := arguments[0]
 
@@ -107,25 +139,33 @@ func runNmap(cmd *exec.Cmd, handle internal.HandleHostFunc) error {
 	cmd.ExtraFiles = []*os.File{writerPipe}
 
 	var wg sync.WaitGroup
+	var parseErr error
 
 	wg.Add(1)
 	go func() {
-		err := internal.ParseNmapXML(readerPipe, handle)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error watching XML: %v\n", err)
+		defer wg.Done()
+
+		parseErr = internal.ParseNmapXML(readerPipe, handle)
+		if parseErr != nil {
+			// keep Nmap from blocking on a full pipe, and the output file complete
+			io.Copy(io.Discard, readerPipe)
 		}
-		wg.Done()
 	}()
 
 	log.Debugf("Running %q ...", cmd)
 	err = cmd.Run()
 	writerPipe.Close()
 
+	// the handler must not be called anymore once runNmap returns
+	wg.Wait()
+
 	if err != nil {
 		return fmt.Errorf("running command %q: %w", cmd, err)
 	}
 
-	wg.Wait()
+	if parseErr != nil {
+		return fmt.Errorf("watching XML: %w", parseErr)
+	}
 
 	return nil
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+
+	"github.com/jojonas/db_nmap/internal"
+)
+
+func TestSplitArguments(t *testing.T) {
+	cases := []struct {
+		args        []string
+		wrapperArgs []string
+		nmapArgs    []string
+	}{
+		{
+			[]string{"--db-dry-run", "-sV", "scanme.nmap.org"},
+			[]string{"--db-dry-run"},
+			[]string{"-sV", "scanme.nmap.org"},
+		},
+		{
+			[]string{"-db-dry-run", "scanme.nmap.org"},
+			[]string{"-db-dry-run"},
+			[]string{"scanme.nmap.org"},
+		},
+		{
+			[]string{"--db-dry-run=false", "scanme.nmap.org"},
+			[]string{"--db-dry-run=false"},
+			[]string{"scanme.nmap.org"},
+		},
+		{
+			[]string{"--db-workspace", "client", "scanme.nmap.org"},
+			[]string{"--db-workspace", "client"},
+			[]string{"scanme.nmap.org"},
+		},
+		{
+			[]string{"--db-workspace=client", "scanme.nmap.org"},
+			[]string{"--db-workspace=client"},
+			[]string{"scanme.nmap.org"},
+		},
+		{
+			[]string{"scanme.nmap.org", "--db-workspace"},
+			[]string{"--db-workspace"},
+			[]string{"scanme.nmap.org"},
+		},
+		{
+			[]string{"-sV", "-p", "22,80", "--db-workspace", "client", "-d", "--datadir", "/tmp", "--db-dry-run", "-oX", "out.xml", "scanme.nmap.org"},
+			[]string{"--db-workspace", "client", "--db-dry-run"},
+			[]string{"-sV", "-p", "22,80", "-d", "--datadir", "/tmp", "-oX", "out.xml", "scanme.nmap.org"},
+		},
+	}
+
+	for _, c := range cases {
+		flags := flag.NewFlagSet("db_nmap", flag.ContinueOnError)
+		internal.RegisterFlags(flags, wrapperFlagPrefix)
+
+		wrapperArgs, nmapArgs := splitArguments(flags, c.args)
+
+		if !reflect.DeepEqual(wrapperArgs, c.wrapperArgs) {
+			t.Errorf("%q: got wrapper arguments %q, expected %q", c.args, wrapperArgs, c.wrapperArgs)
+		}
+
+		if !reflect.DeepEqual(nmapArgs, c.nmapArgs) {
+			t.Errorf("%q: got Nmap arguments %q, expected %q", c.args, nmapArgs, c.nmapArgs)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// compressions are the supported compression formats, identified by the magic
+// bytes at the beginning of the data.
+var compressions = []struct {
+	name  string
+	sniff func(magic []byte) bool
+	open  func(reader io.Reader) (io.ReadCloser, error)
+}{
+	{"gzip", hasMagic(0x1f, 0x8b), func(reader io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(reader)
+	}},
+	{"bzip2", sniffBzip2, func(reader io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(bzip2.NewReader(reader)), nil
+	}},
+	{"xz", hasMagic(0xfd, '7', 'z', 'X', 'Z', 0x00), func(reader io.Reader) (io.ReadCloser, error) {
+		xzReader, err := xz.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xzReader), nil
+	}},
+	{"zstd", hasMagic(0x28, 0xb5, 0x2f, 0xfd), func(reader io.Reader) (io.ReadCloser, error) {
+		decoder, err := zstd.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}},
+}
+
+func hasMagic(magic ...byte) func(prefix []byte) bool {
+	return func(prefix []byte) bool {
+		return bytes.HasPrefix(prefix, magic)
+	}
+}
+
+// sniffBzip2 checks the magic "BZh" followed by the block size ('1' to '9'),
+// so that text starting with "BZh" is not taken for bzip2.
+func sniffBzip2(prefix []byte) bool {
+	return len(prefix) >= 4 && bytes.HasPrefix(prefix, []byte("BZh")) && prefix[3] >= '1' && prefix[3] <= '9'
+}
+
+// Decompress detects compressed data by its magic bytes and returns a reader
+// that decompresses it while reading. Uncompressed data is passed through.
+func Decompress(reader io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(reader)
+
+	magic, _ := buffered.Peek(6)
+
+	for _, compression := range compressions {
+		if !compression.sniff(magic) {
+			continue
+		}
+
+		log.Debugf("Decompressing %s data.", compression.name)
+
+		decompressed, err := compression.open(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("opening %s data: %w", compression.name, err)
+		}
+		return decompressed, nil
+	}
+
+	return io.NopCloser(buffered), nil
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecompress(t *testing.T) {
+	for _, filename := range []string{"testdata/scanme.xml.gz", "testdata/masscan.json.bz2", "testdata/scanme.gnmap.xz", "testdata/rustscan.txt.zst"} {
+		t.Run(filename, func(t *testing.T) {
+			uncompressed := strings.TrimSuffix(filename, filename[strings.LastIndex(filename, "."):])
+
+			expected := parseTestdata(t, uncompressed)
+			hosts := parseTestdata(t, filename)
+
+			if len(hosts) == 0 || !reflect.DeepEqual(hosts, expected) {
+				t.Errorf("Got %d hosts that differ from the %d uncompressed ones", len(hosts), len(expected))
+			}
+		})
+	}
+}
+
+func TestDecompressUncompressed(t *testing.T) {
+	for _, input := range []string{"BZh is not bzip2", "BZ", ""} {
+		reader, err := Decompress(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("Error decompressing %q: %v", input, err)
+		}
+
+		output, err := io.ReadAll(reader)
+		if err != nil || string(output) != input {
+			t.Errorf("Got %q (error %v), expected %q to be passed through", output, err, input)
+		}
+	}
+}
+
+func TestReadCompressedArgs(t *testing.T) {
+	reader, err := os.Open("testdata/scanme.xml.gz")
+	if err != nil {
+		t.Fatalf("Error opening testdata/scanme.xml.gz: %v", err)
+	}
+	defer reader.Close()
+
+	args, err := ReadNmapArgs(reader)
+	if err != nil {
+		t.Fatalf("Error reading args: %v", err)
+	}
+
+	expected := "/usr/bin/nmap -sV -oX /dev/fd/3 scanme.nmap.org"
+	if args != expected {
+		t.Errorf("Got args %q, expected %q", args, expected)
+	}
+}
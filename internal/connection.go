@@ -2,8 +2,12 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/stdlib"
@@ -13,59 +17,142 @@ import (
 )
 
 var ConnString = ""
-var MetasploitDatabaseConfigurationFile = "/usr/share/metasploit-framework/config/database.yml"
 
+const DatabaseConfigEnvVar = "MSF_DATABASE_CONFIG"
+const DatabaseEnvironmentEnvVar = "MSF_DATABASE_ENVIRONMENT"
 const WorkspaceEnvVar = "MSF_WORKSPACE"
 
+// MetasploitDatabaseConfigurationFile is the database.yml to read. If empty,
+// the first existing file of MetasploitDatabaseConfigurationFiles is used.
+var MetasploitDatabaseConfigurationFile = envString(DatabaseConfigEnvVar, "")
+
+// MetasploitDatabaseConfigurationFiles are the standard locations of
+// database.yml, in the order they are searched. A leading "~" stands for the
+// home directory of the user (and of the user who invoked sudo).
+var MetasploitDatabaseConfigurationFiles = []string{
+	"~/.msf4/database.yml",
+	"/usr/share/metasploit-framework/config/database.yml",
+	"/opt/metasploit-framework/embedded/framework/config/database.yml",
+}
+
+// MetasploitDatabaseEnvironment is the section of database.yml to use.
+var MetasploitDatabaseEnvironment = envString(DatabaseEnvironmentEnvVar, "production")
+
+// Workspace is the name of the destination workspace.
+var Workspace = envString(WorkspaceEnvVar, "default")
+
+// MetasploitDatabaseConfigSection holds the settings of one environment in
+// database.yml. Empty settings fall back to PostgreSQL's environment
+// variables (e.g. PGPORT or PGSSLMODE) and defaults.
 type MetasploitDatabaseConfigSection struct {
-	Adapter  string
-	Database string
-	Username string
-	Password string
-	Host     string
-	Port     uint16
-	Pool     int
-	Timeout  int
+	Adapter     string
+	Database    string
+	Username    string
+	Password    string
+	Host        string
+	Port        uint16
+	Pool        int
+	Timeout     int
+	SSLMode     string `yaml:"sslmode"`
+	SSLRootCert string `yaml:"sslrootcert"`
+}
+
+// ConnConfig converts the section into a pgx connection configuration.
+// Timeout is used as the connect timeout in seconds.
+func (section MetasploitDatabaseConfigSection) ConnConfig() (*pgx.ConnConfig, error) {
+	settings := []struct {
+		key   string
+		value string
+	}{
+		{"host", section.Host},
+		{"dbname", section.Database},
+		{"user", section.Username},
+		{"password", section.Password},
+		{"sslmode", section.SSLMode},
+		{"sslrootcert", section.SSLRootCert},
+	}
+
+	parts := make([]string, 0, len(settings)+2)
+	for _, setting := range settings {
+		if setting.value != "" {
+			parts = append(parts, setting.key+"="+quoteConnValue(setting.value))
+		}
+	}
+	if section.Port != 0 {
+		parts = append(parts, fmt.Sprintf("port=%d", section.Port))
+	}
+	if section.Timeout > 0 {
+		parts = append(parts, fmt.Sprintf("connect_timeout=%d", section.Timeout))
+	}
+
+	return pgx.ParseConfig(strings.Join(parts, " "))
 }
 
-type MetasploitDatabaseConfig struct {
-	Development MetasploitDatabaseConfigSection
-	Production  MetasploitDatabaseConfigSection
-	Test        MetasploitDatabaseConfigSection
+// quoteConnValue quotes value for a key=value connection string.
+func quoteConnValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
 }
 
-func Connect(ctx context.Context) (*gorm.DB, int, error) {
+// MetasploitDatabaseConfig maps environment names (e.g. "production") to
+// their database settings.
+type MetasploitDatabaseConfig map[string]MetasploitDatabaseConfigSection
+
+// Connect opens the Metasploit database and returns it together with the ID
+// of the destination workspace. If CreateMissingWorkspace is set, a missing
+// workspace is created with the targets as its boundary. In DryRun mode, the
+// connection is read-only. Passwords are redacted from the returned error.
+func Connect(ctx context.Context, targets []string) (*gorm.DB, int, error) {
+	db, workspaceId, err := connect(ctx, targets)
+	if err != nil {
+		return nil, 0, redactedError{err}
+	}
+	return db, workspaceId, nil
+}
+
+func connect(ctx context.Context, targets []string) (*gorm.DB, int, error) {
 	var err error
 	var pgxCfg *pgx.ConnConfig
+	pool := 0
 
 	if ConnString != "" {
-		log.Infof("Connecting with PostgreSQL connection string: %q", ConnString)
+		log.Infof("Connecting with PostgreSQL connection string: %q", RedactPasswords(ConnString))
 
 		pgxCfg, err = pgx.ParseConfig(ConnString)
 		if err != nil {
-			return nil, 0, fmt.Errorf("parsing PostgreSQL connection string %q: %w", ConnString, err)
+			return nil, 0, fmt.Errorf("parsing PostgreSQL connection string %q: %w", RedactPasswords(ConnString), err)
 		}
-	} else {
-		pgxCfg, err = readMetasploitConfiguration(MetasploitDatabaseConfigurationFile)
+	} else if filename := findMetasploitConfiguration(); filename != "" {
+		section, err := readMetasploitConfiguration(filename, MetasploitDatabaseEnvironment)
+		if err == nil {
+			pgxCfg, err = section.ConnConfig()
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("parsing Metasploit database configuration file %q: %w", filename, err)
+		}
+		pool = section.Pool
 
+		log.Infof("Read environment %q of Metasploit database configuration file %q.", MetasploitDatabaseEnvironment, filename)
+	} else {
+		log.Info("Creating default config...")
+		pgxCfg, err = pgx.ParseConfig("")
 		if err != nil {
-			if os.IsNotExist(err) {
-				log.Info("Creating default config...")
-				pgxCfg, err = pgx.ParseConfig("")
-				if err != nil {
-					return nil, 0, fmt.Errorf("creating default config: %w", err)
-				}
-			} else {
-				return nil, 0, fmt.Errorf("parsing Metasploit database configuration file %q: %w", MetasploitDatabaseConfigurationFile, err)
-			}
-		} else {
-			log.Infof("Read Metasploit database configuration file %q.", MetasploitDatabaseConfigurationFile)
+			return nil, 0, fmt.Errorf("creating default config: %w", err)
 		}
 	}
 
 	log.Infof("Connecting to Metasploit PostgreSQL database %q at %s:%d as user %q", pgxCfg.Database, pgxCfg.Host, pgxCfg.Port, pgxCfg.User)
 
+	if DryRun {
+		log.Info("Dry run: connecting read-only.")
+		pgxCfg.RuntimeParams["default_transaction_read_only"] = "on"
+	}
+
 	sqlConn := stdlib.OpenDB(*pgxCfg)
+	if pool > 0 {
+		sqlConn.SetMaxOpenConns(pool)
+	}
 	gormDb, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlConn}), &gorm.Config{})
 	if err != nil {
 		return nil, 0, fmt.Errorf("connect to PostgreSQL database: %w", err)
@@ -74,12 +161,24 @@ func Connect(ctx context.Context) (*gorm.DB, int, error) {
 	// use this to print all queries
 	// gormDb = gormDb.Debug()
 
-	workspace := os.Getenv(WorkspaceEnvVar)
-	if workspace == "" {
-		workspace = "default"
-	}
+	workspace := Workspace
 
 	workspaceId, err := GetWorkspaceId(gormDb, workspace)
+	if errors.Is(err, gorm.ErrRecordNotFound) && CreateMissingWorkspace && DryRun {
+		// no host belongs to workspace ID 0, so all of them would be created
+		log.Infof("Dry run: would create Metasploit workspace %q.", workspace)
+		return gormDb, 0, nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) && CreateMissingWorkspace {
+		description := fmt.Sprintf("Created by %s", filepath.Base(os.Args[0]))
+
+		workspaceId, err = InsertWorkspace(gormDb, workspace, description, targets)
+		if err != nil {
+			return nil, 0, fmt.Errorf("creating Metasploit workspace %q: %w", workspace, err)
+		}
+
+		log.Infof("Created Metasploit workspace %q.", workspace)
+	}
 	if err != nil {
 		return nil, 0, fmt.Errorf("reading ID of Metasploit workspace %q: %w", workspace, err)
 	}
@@ -89,28 +188,67 @@ func Connect(ctx context.Context) (*gorm.DB, int, error) {
 	return gormDb, workspaceId, nil
 }
 
-func readMetasploitConfiguration(filename string) (*pgx.ConnConfig, error) {
+// findMetasploitConfiguration returns MetasploitDatabaseConfigurationFile if
+// it is set, or else the first existing standard location. It returns an
+// empty string if there is no configuration file.
+func findMetasploitConfiguration() string {
+	if MetasploitDatabaseConfigurationFile != "" {
+		return MetasploitDatabaseConfigurationFile
+	}
+
+	for _, candidate := range MetasploitDatabaseConfigurationFiles {
+		for _, filename := range expandHome(candidate) {
+			if _, err := os.Stat(filename); err == nil {
+				return filename
+			}
+			log.Debugf("No Metasploit database configuration file at %q.", filename)
+		}
+	}
+
+	return ""
+}
+
+// expandHome replaces a leading "~" in filename with the home directory of the
+// current user and, if running under sudo, of the invoking user.
+func expandHome(filename string) []string {
+	rest, ok := strings.CutPrefix(filename, "~")
+	if !ok {
+		return []string{filename}
+	}
+
+	homes := make([]string, 0, 2)
+	if home, err := os.UserHomeDir(); err == nil {
+		homes = append(homes, home)
+	}
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
+		if u, err := user.Lookup(sudoUser); err == nil && u.HomeDir != "" {
+			homes = appendUnique(homes, u.HomeDir)
+		}
+	}
+
+	filenames := make([]string, 0, len(homes))
+	for _, home := range homes {
+		filenames = append(filenames, filepath.Join(home, rest))
+	}
+	return filenames
+}
+
+func readMetasploitConfiguration(filename string, environment string) (MetasploitDatabaseConfigSection, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("reading %s: %w", filename, err)
+		return MetasploitDatabaseConfigSection{}, fmt.Errorf("reading %s: %w", filename, err)
 	}
 
 	msfConfig := MetasploitDatabaseConfig{}
 	err = yaml.Unmarshal(data, &msfConfig)
 	if err != nil {
-		return nil, fmt.Errorf("parsing YAML in %s: %w", filename, err)
+		return MetasploitDatabaseConfigSection{}, fmt.Errorf("parsing YAML in %s: %w", filename, err)
 	}
 
-	dbConfig, err := pgx.ParseConfig("")
-	if err != nil {
-		return nil, err
+	section, ok := msfConfig[environment]
+	if !ok {
+		return MetasploitDatabaseConfigSection{}, fmt.Errorf("environment %q not found in %s", environment, filename)
 	}
 
-	dbConfig.Host = msfConfig.Production.Host
-	dbConfig.Port = msfConfig.Production.Port
-	dbConfig.Database = msfConfig.Production.Database
-	dbConfig.User = msfConfig.Production.Username
-	dbConfig.Password = msfConfig.Production.Password
-
-	return dbConfig, nil
+	return section, nil
 }
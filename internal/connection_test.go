@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadMetasploitConfiguration(t *testing.T) {
+	t.Setenv("PGPORT", "")
+	t.Setenv("PGSSLMODE", "")
+
+	section, err := readMetasploitConfiguration("testdata/database.yml", "production")
+	if err != nil {
+		t.Fatalf("Error reading configuration: %v", err)
+	}
+	if section.Pool != 200 || section.Timeout != 5 {
+		t.Errorf("Wrong pool %d or timeout %d", section.Pool, section.Timeout)
+	}
+
+	cfg, err := section.ConnConfig()
+	if err != nil {
+		t.Fatalf("Error converting configuration: %v", err)
+	}
+	if cfg.Database != "msf" || cfg.User != "msf" || cfg.Password != "secret" || cfg.Host != "127.0.0.1" || cfg.Port != 5433 {
+		t.Errorf("Wrong production configuration: %s@%s:%d/%s", cfg.User, cfg.Host, cfg.Port, cfg.Database)
+	}
+	if cfg.ConnectTimeout != 5*time.Second {
+		t.Errorf("Wrong connect timeout: %v", cfg.ConnectTimeout)
+	}
+
+	section, err = readMetasploitConfiguration("testdata/database.yml", "test")
+	if err != nil {
+		t.Fatalf("Error reading configuration: %v", err)
+	}
+
+	cfg, err = section.ConnConfig()
+	if err != nil {
+		t.Fatalf("Error converting configuration: %v", err)
+	}
+	if cfg.Database != "msftest" || cfg.User != "msftest" || cfg.Password != `it's a \ secret` || cfg.Port != 5432 {
+		t.Errorf("Wrong test configuration: %s:%q@%s:%d/%s", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Database)
+	}
+	if cfg.TLSConfig == nil {
+		t.Errorf("Expected TLS for sslmode require")
+	}
+
+	_, err = readMetasploitConfiguration("testdata/database.yml", "staging")
+	if err == nil {
+		t.Errorf("Expected error for missing environment")
+	}
+}
+
+func TestFindMetasploitConfiguration(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SUDO_USER", "")
+
+	defer func(file string, files []string) {
+		MetasploitDatabaseConfigurationFile = file
+		MetasploitDatabaseConfigurationFiles = files
+	}(MetasploitDatabaseConfigurationFile, MetasploitDatabaseConfigurationFiles)
+
+	MetasploitDatabaseConfigurationFile = ""
+	MetasploitDatabaseConfigurationFiles = []string{"~/.msf4/database.yml", "testdata/database.yml"}
+
+	if found := findMetasploitConfiguration(); found != "testdata/database.yml" {
+		t.Errorf("Expected fallback to testdata/database.yml, got %q", found)
+	}
+
+	userConfig := filepath.Join(home, ".msf4", "database.yml")
+	if err := os.MkdirAll(filepath.Dir(userConfig), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(userConfig, []byte("production: {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if found := findMetasploitConfiguration(); found != userConfig {
+		t.Errorf("Expected %q, got %q", userConfig, found)
+	}
+
+	MetasploitDatabaseConfigurationFile = "/nonexistent/database.yml"
+	if found := findMetasploitConfiguration(); found != MetasploitDatabaseConfigurationFile {
+		t.Errorf("Expected override %q, got %q", MetasploitDatabaseConfigurationFile, found)
+	}
+}
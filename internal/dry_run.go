@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// DryRunHost reports what InsertHost would change in the database: for the
+// host and each of its services, whether it would be created, updated (with
+// the changed fields) or left unchanged. It only reads from db, which should
+// be connected read-only. It returns the number of services that would be
+// imported.
+func DryRunHost(db *gorm.DB, workspaceId int, nmapHost NmapHost) (int, error) {
+	preferredIP := nmapHost.PreferredIPAddress()
+
+	msfHost, found, err := findExistingHost(db, workspaceId, preferredIP)
+	if err != nil {
+		return 0, err
+	}
+
+	if nmapHost.Status.State != "up" {
+		if !found {
+			log.Debugf("Host %s is %s and unknown, skipping.", nmapHost, nmapHost.Status.State)
+			return 0, nil
+		}
+
+		updated := msfHost
+		updated.State = msfHostState(nmapHost.Status.State)
+		reportDryRun(fmt.Sprintf("host %s", nmapHost), found, hostChanges(msfHost, updated))
+		return 0, nil
+	}
+
+	if !shouldImportHost(nmapHost) {
+		if ReconcileServices && found {
+			return 0, dryRunReconcile(db, msfHost, nmapHost)
+		}
+
+		log.Debugf("Host %s does not have any open ports, skipping.", nmapHost)
+		return 0, nil
+	}
+
+	updated := msfHost
+	updateMsfHost(&updated, nmapHost)
+	reportDryRun(fmt.Sprintf("host %s", nmapHost), found, hostChanges(msfHost, updated))
+
+	serviceCount := 0
+	for _, port := range nmapHost.Ports.Port {
+		if !shouldImportService(port) {
+			continue
+		}
+
+		var msfService MsfService
+		serviceFound := false
+		if found {
+			msfService, serviceFound, err = findExistingService(db, msfHost.Id, port.Protocol, port.Portid)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		updatedService := msfService
+		updateMsfService(&updatedService, port)
+		reportDryRun(fmt.Sprintf("service %s/%d of host %s", port.Protocol, port.Portid, nmapHost), serviceFound, serviceChanges(msfService, updatedService))
+
+		serviceCount++
+	}
+
+	if ReconcileServices && found {
+		err = dryRunReconcile(db, msfHost, nmapHost)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return serviceCount, nil
+}
+
+// dryRunReconcile reports the services reconcileServices would close.
+func dryRunReconcile(db *gorm.DB, msfHost MsfHost, nmapHost NmapHost) error {
+	var msfServices []MsfService
+
+	err := db.Where("host_id = ? AND state = ?", msfHost.Id, "open").Find(&msfServices).Error
+	if err != nil {
+		return fmt.Errorf("query open services: %w", err)
+	}
+
+	for _, msfService := range reconciledServices(msfServices, nmapHost) {
+		log.Infof("Dry run: would update service %s/%d of host %s: state %q -> %q", msfService.Proto, msfService.Port, nmapHost, "open", msfService.State)
+	}
+
+	return nil
+}
+
+// findExistingService looks up a service without creating it.
+func findExistingService(db *gorm.DB, hostId int, proto string, port int) (MsfService, bool, error) {
+	var msfService MsfService
+
+	result := db.
+		Where("host_id = ? AND proto = ? AND port = ?", hostId, proto, port).
+		Limit(1).
+		Find(&msfService)
+	if result.Error != nil {
+		return msfService, false, fmt.Errorf("query service %s/%d: %w", proto, port, result.Error)
+	}
+
+	return msfService, result.RowsAffected > 0, nil
+}
+
+// fieldChange is a column whose value would change.
+type fieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+func (c fieldChange) String() string {
+	return fmt.Sprintf("%s %q -> %q", c.Field, c.Old, c.New)
+}
+
+// changedFields returns the fields whose value differs.
+func changedFields(fields ...fieldChange) []fieldChange {
+	changes := make([]fieldChange, 0)
+	for _, field := range fields {
+		if field.Old != field.New {
+			changes = append(changes, field)
+		}
+	}
+	return changes
+}
+
+func hostChanges(old MsfHost, new MsfHost) []fieldChange {
+	return changedFields(
+		fieldChange{"mac", old.MAC, new.MAC},
+		fieldChange{"name", old.Name, new.Name},
+		fieldChange{"state", old.State, new.State},
+		fieldChange{"os_name", old.OSName, new.OSName},
+		fieldChange{"os_flavor", old.OSFlavor, new.OSFlavor},
+		fieldChange{"os_sp", old.OSSp, new.OSSp},
+		fieldChange{"os_lang", old.OSLang, new.OSLang},
+		fieldChange{"os_family", old.OSFamily, new.OSFamily},
+		fieldChange{"arch", old.Arch, new.Arch},
+		fieldChange{"purpose", old.Purpose, new.Purpose},
+	)
+}
+
+func serviceChanges(old MsfService, new MsfService) []fieldChange {
+	return changedFields(
+		fieldChange{"state", old.State, new.State},
+		fieldChange{"name", old.Name, new.Name},
+		fieldChange{"info", old.Info, new.Info},
+	)
+}
+
+// reportDryRun logs whether the described row would be created, updated or
+// left unchanged.
+func reportDryRun(description string, found bool, changes []fieldChange) {
+	parts := make([]string, 0, len(changes))
+
+	if !found {
+		for _, change := range changes {
+			parts = append(parts, fmt.Sprintf("%s %q", change.Field, change.New))
+		}
+		log.Infof("Dry run: would create %s: %s", description, strings.Join(parts, ", "))
+		return
+	}
+
+	if len(changes) == 0 {
+		log.Infof("Dry run: %s is unchanged.", description)
+		return
+	}
+
+	for _, change := range changes {
+		parts = append(parts, change.String())
+	}
+	log.Infof("Dry run: would update %s: %s", description, strings.Join(parts, ", "))
+}
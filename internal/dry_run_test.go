@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestDryRunReport(t *testing.T) {
+	defer func(logger *logrus.Logger) {
+		log = logger
+	}(log)
+
+	var hook *test.Hook
+	log, hook = test.NewNullLogger()
+
+	old := MsfService{State: "open", Name: "http", Info: "nginx"}
+
+	var service NmapService
+	service.State.State = "open"
+	service.Service.Name = "http"
+	service.Service.Tunnel = "ssl"
+	service.Service.Product = "nginx"
+	service.Service.Version = "1.25.3"
+
+	updated := old
+	updateMsfService(&updated, service)
+
+	reportDryRun("service tcp/443", true, serviceChanges(old, updated))
+	expected := `Dry run: would update service tcp/443: name "http" -> "ssl/http", info "nginx" -> "nginx 1.25.3"`
+	if message := hook.LastEntry().Message; message != expected {
+		t.Errorf("Got %q, expected %q", message, expected)
+	}
+
+	reportDryRun("service tcp/443", true, serviceChanges(updated, updated))
+	expected = "Dry run: service tcp/443 is unchanged."
+	if message := hook.LastEntry().Message; message != expected {
+		t.Errorf("Got %q, expected %q", message, expected)
+	}
+
+	reportDryRun("service tcp/443", false, serviceChanges(MsfService{}, updated))
+	expected = `Dry run: would create service tcp/443: state "open", name "ssl/http", info "nginx 1.25.3"`
+	if message := hook.LastEntry().Message; message != expected {
+		t.Errorf("Got %q, expected %q", message, expected)
+	}
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"flag"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RegisterFlags registers command-line flags for the connection and import
+// options. Their names are prefixed with prefix (e.g. "db-"). The defaults
+// are the values from the environment.
+func RegisterFlags(flags *flag.FlagSet, prefix string) {
+	flags.StringVar(&Workspace, prefix+"workspace", Workspace,
+		"Metasploit workspace to import into (environment: "+WorkspaceEnvVar+")")
+	flags.StringVar(&MetasploitDatabaseConfigurationFile, prefix+"config", MetasploitDatabaseConfigurationFile,
+		"Metasploit database configuration file (database.yml), instead of searching ~/.msf4 and the installation directories (environment: "+DatabaseConfigEnvVar+")")
+	flags.StringVar(&MetasploitDatabaseEnvironment, prefix+"environment", MetasploitDatabaseEnvironment,
+		"section of the database configuration file, e.g. production or development (environment: "+DatabaseEnvironmentEnvVar+")")
+	flags.Var(connStringValue{&ConnString}, prefix+"conn",
+		"PostgreSQL connection string, overrides the database configuration file")
+	flags.Func(prefix+"log-level", "log level (trace, debug, info, warn, error) (default \""+Logger.GetLevel().String()+"\")", func(value string) error {
+		level, err := logrus.ParseLevel(value)
+		if err != nil {
+			return err
+		}
+		Logger.SetLevel(level)
+		return nil
+	})
+	flags.BoolVar(&DryRun, prefix+"dry-run", DryRun,
+		"connect read-only and report which hosts and services would be created or updated")
+
+	flags.BoolVar(&IncludeClosedPorts, prefix+"include-closed", IncludeClosedPorts,
+		"import closed and filtered services and hosts without open services (environment: "+IncludeClosedPortsEnvVar+")")
+	flags.BoolVar(&ReconcileServices, prefix+"reconcile", ReconcileServices,
+		"mark open services as closed if their port was scanned, but not found open (environment: "+ReconcileServicesEnvVar+")")
+	flags.IntVar(&MinOSAccuracy, prefix+"min-os-accuracy", MinOSAccuracy,
+		"minimum accuracy in percent of OS matches (environment: "+MinOSAccuracyEnvVar+")")
+	flags.BoolVar(&PreferIPv6, prefix+"prefer-ipv6", PreferIPv6,
+		"identify dual-stack hosts by their IPv6 address (environment: "+PreferIPv6EnvVar+")")
+	flags.BoolVar(&RegisterRouters, prefix+"register-routers", RegisterRouters,
+		"create hosts for intermediate traceroute hops (environment: "+RegisterRoutersEnvVar+")")
+	flags.BoolVar(&CreateMissingWorkspace, prefix+"create-workspace", CreateMissingWorkspace,
+		"create the workspace if it does not exist (environment: "+CreateMissingWorkspaceEnvVar+")")
+	flags.BoolVar(&Strict, prefix+"strict", Strict,
+		"exit with a non-zero code if any host could not be imported (environment: "+StrictEnvVar+")")
+}
+
+// IsBoolFlag returns true if the flag does not take a value.
+func IsBoolFlag(flags *flag.FlagSet, name string) bool {
+	f := flags.Lookup(name)
+	if f == nil {
+		return false
+	}
+
+	boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
@@ -1,10 +1,12 @@
 package internal
 
 import (
+	"bufio"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
 var TestedVersions = []string{"7.40", "7.70", "7.80", "7.92", "7.93"}
@@ -13,6 +15,7 @@ type HandleHostFunc func(host NmapHost) error
 
 func ParseNmapXML(reader io.Reader, handle HandleHostFunc) error {
 	decoder := xml.NewDecoder(reader)
+	scaninfo := make([]NmapScaninfo, 0)
 
 outer:
 	for {
@@ -35,12 +38,28 @@ outer:
 		case xml.StartElement:
 			switch t.Name.Local {
 			case "nmaprun":
+				scanner, version := "nmap", ""
 				for _, attr := range t.Attr {
-					if attr.Name.Local == "version" {
-						checkVersion(attr.Value)
-						break
+					switch attr.Name.Local {
+					case "scanner":
+						scanner = attr.Value
+					case "version":
+						version = attr.Value
 					}
 				}
+
+				// masscan writes the same document element
+				if scanner == "nmap" {
+					checkVersion(version)
+				}
+			case "scaninfo":
+				info := NmapScaninfo{}
+				err = decoder.DecodeElement(&info, &t)
+				if err != nil {
+					return fmt.Errorf("reading <scaninfo>: %w", err)
+				}
+
+				scaninfo = append(scaninfo, info)
 			case "host":
 				host := NmapHost{}
 				err = decoder.DecodeElement(&host, &t)
@@ -48,6 +67,8 @@ outer:
 					return fmt.Errorf("reading <host>: %w", err)
 				}
 
+				host.Scaninfo = scaninfo
+
 				err := handle(host)
 				if err != nil {
 					return fmt.Errorf("handling <host>: %w", err)
@@ -66,6 +87,59 @@ outer:
 	return nil
 }
 
+// ReadNmapArgs returns the command line of the scan from the <nmaprun>
+// element or the header of grepable output, without reading any further.
+// The other formats do not record it.
+func ReadNmapArgs(reader io.Reader) (string, error) {
+	decompressed, err := Decompress(reader)
+	if err != nil {
+		return "", err
+	}
+	defer decompressed.Close()
+
+	buffered := bufio.NewReaderSize(decompressed, sniffLength)
+
+	parser, err := DetectParser(buffered)
+	if err != nil {
+		return "", err
+	}
+
+	switch parser.Name() {
+	case formatNmapXML, formatMasscanXML:
+	case formatNmapGrepable:
+		line, err := buffered.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("reading header: %w", err)
+		}
+
+		match := grepableHeaderRegexp.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if match == nil {
+			return "", nil
+		}
+		return match[2], nil
+	default:
+		return "", nil
+	}
+
+	decoder := xml.NewDecoder(buffered)
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("reading token: %w", err)
+		}
+
+		if t, ok := token.(xml.StartElement); ok && t.Name.Local == "nmaprun" {
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "args" {
+					return attr.Value, nil
+				}
+			}
+			return "", nil
+		}
+	}
+}
+
 func checkVersion(version string) {
 	isTested := false
 	for _, testedVersions := range TestedVersions {
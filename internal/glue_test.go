@@ -12,7 +12,7 @@ import (
 func TestParse(t *testing.T) {
 	log.SetLevel(logrus.DebugLevel)
 
-	files := []string{"testdata/scanme.xml", "testdata/localhost.xml"}
+	files := []string{"testdata/scanme.xml", "testdata/localhost.xml", "testdata/smb.xml", "testdata/vulns.xml"}
 
 	for _, filename := range files {
 		t.Run(filename, func(t *testing.T) {
@@ -23,7 +23,7 @@ func TestParse(t *testing.T) {
 			defer reader.Close()
 
 			hosts := 0
-			err = ParseNmapXML(reader, func(host NmapHost) error {
+			err = ParseScanResults(reader, func(host NmapHost) error {
 				hosts++
 				return nil
 			})
@@ -39,6 +39,74 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseHostscript(t *testing.T) {
+	scripts := parseTestdata(t, "testdata/smb.xml")[0].Hostscript.Script
+
+	if len(scripts) != 4 {
+		t.Fatalf("Expected 4 host scripts, got %d", len(scripts))
+	}
+
+	if scripts[0].ID != "smb-os-discovery" {
+		t.Errorf("Expected first host script to be smb-os-discovery, got %q", scripts[0].ID)
+	}
+
+	if !strings.Contains(scripts[0].FullOutput(), "Computer name: WIN-SRV01") {
+		t.Errorf("Unexpected output of smb-os-discovery: %q", scripts[0].FullOutput())
+	}
+}
+
+func TestScriptLookup(t *testing.T) {
+	hosts := make([]NmapHost, 0)
+	for _, filename := range []string{"testdata/smb.xml", "testdata/vulns.xml"} {
+		hosts = append(hosts, parseTestdata(t, filename)...)
+	}
+
+	cases := []struct {
+		scripts  NmapScripts
+		path     string
+		expected string
+	}{
+		{hosts[0].Hostscript.Script, "smb-os-discovery.fqdn", "WIN-SRV01"},
+		{hosts[0].Hostscript.Script, "nbstat.mac.address", "08:00:27:3a:4b:5c"},
+		{hosts[0].Hostscript.Script, "smb2-security-mode.210.1", "Message signing enabled but not required"},
+		{hosts[1].Ports.Port[0].Script, "vulners.cpe:/a:openbsd:openssh:6.6.1p1.2.id", "EDB-ID:40888"},
+		{hosts[1].Hostscript.Script, "smb-vuln-ms17-010.CVE-2017-0143.dates.disclosure.year", "2017"},
+	}
+
+	for _, c := range cases {
+		value, ok := c.scripts.Lookup(c.path)
+		if !ok {
+			t.Errorf("Path %q not found", c.path)
+			continue
+		}
+
+		if value != c.expected {
+			t.Errorf("Path %q: got %v, expected %q", c.path, value, c.expected)
+		}
+	}
+
+	if _, ok := hosts[0].Hostscript.Script.Lookup("nbstat.mac.vendor"); ok {
+		t.Error("Lookup of a missing key succeeded")
+	}
+
+	if _, ok := hosts[0].Hostscript.Script.Lookup("smb2-security-mode.210.0"); ok {
+		t.Error("Lookup of list position 0 succeeded")
+	}
+}
+
+func TestServiceInfo(t *testing.T) {
+	ssh := parseTestdata(t, "testdata/scanme.xml")[0].Ports.Port[0]
+
+	expected := "OpenSSH 6.6.1p1 Ubuntu 2ubuntu2.13 Ubuntu Linux; protocol 2.0"
+	if ssh.Info() != expected {
+		t.Errorf("Got info %q, expected %q", ssh.Info(), expected)
+	}
+
+	if len(ssh.Service.Cpe) != 2 || ssh.Service.Cpe[0] != "cpe:/a:openbsd:openssh:6.6.1p1" {
+		t.Errorf("Unexpected CPEs: %v", ssh.Service.Cpe)
+	}
+}
+
 func TestCheckVersion(t *testing.T) {
 	var hook *test.Hook
 	log, hook = test.NewNullLogger()
@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"os"
+	"testing"
+)
+
+// parseTestdata parses a file from testdata and returns all of its hosts.
+func parseTestdata(t *testing.T, filename string) []NmapHost {
+	t.Helper()
+
+	reader, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("Error opening %q: %v", filename, err)
+	}
+	defer reader.Close()
+
+	var hosts []NmapHost
+	err = ParseScanResults(reader, func(host NmapHost) error {
+		hosts = append(hosts, host)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error parsing %q: %v", filename, err)
+	}
+
+	return hosts
+}
@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// masscanTunnelBanners are the banner types masscan reports for TLS.
+var masscanTunnelBanners = map[string]bool{"ssl": true, "X509": true, "X509CA": true}
+
+// masscanInfoBanners are banner types that do not name the service.
+var masscanInfoBanners = map[string]bool{"title": true, "http.server": true}
+
+// addMasscanPort merges a port record of masscan into hosts.
+func addMasscanPort(hosts *mergedHosts, address string, port NmapService) {
+	host := hosts.host(address)
+	service := hosts.port(host, port.Protocol, port.Portid)
+
+	// banners are only grabbed from open ports, and do not have a state
+	if port.State.State != "" {
+		service.State = port.State
+	}
+
+	applyMasscanBanner(service, port.Service.Name, port.Service.Banner)
+}
+
+// applyMasscanBanner stores a banner of the given type (e.g. "ssh" or
+// "title") on the service. The banners are collected in the output of a
+// "banner" script, like Nmap's script of the same name.
+func applyMasscanBanner(service *NmapService, name string, banner string) {
+	switch {
+	case name == "":
+	case masscanTunnelBanners[name]:
+		service.Service.Tunnel = "ssl"
+	case masscanInfoBanners[name]:
+	default:
+		if service.Service.Name == "" {
+			service.Service.Name = name
+		}
+	}
+
+	banner = strings.TrimSpace(banner)
+	if banner == "" {
+		return
+	}
+
+	line := fmt.Sprintf("%s: %s", name, banner)
+
+	for i := range service.Script {
+		if service.Script[i].ID == "banner" {
+			service.Script[i].Output += "\n" + line
+			return
+		}
+	}
+
+	service.Script = append(service.Script, NmapScript{ID: "banner", Output: line})
+}
+
+func sniffMasscanXML(prefix []byte) bool {
+	return bytes.HasPrefix(prefix, []byte("<")) && bytes.Contains(prefix, []byte(`scanner="masscan"`))
+}
+
+func sniffMasscanJSON(prefix []byte) bool {
+	line := firstJSONLine(prefix)
+	return hasJSONKeys(line, "ip", "ports") || hasJSONKeys(line, "ip", "rec_type")
+}
+
+// ParseMasscanXML reads masscan's XML output (-oX). It resembles Nmap's, but
+// lists every port and banner as a separate host. Since these are merged, all
+// hosts are handled at the end.
+func ParseMasscanXML(reader io.Reader, handle HandleHostFunc) error {
+	hosts := newMergedHosts()
+
+	err := ParseNmapXML(reader, func(record NmapHost) error {
+		if len(record.Address) == 0 {
+			return nil
+		}
+
+		for _, port := range record.Ports.Port {
+			addMasscanPort(hosts, record.Address[0].Addr, port)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return hosts.handleAll(handle)
+}
+
+// masscanRecord is a record of masscan's JSON (-oJ) output, with a list of
+// ports, or of its NDJSON (-oD) output, with one port and the details in data.
+type masscanRecord struct {
+	IP    string `json:"ip"`
+	Ports []struct {
+		Port    int    `json:"port"`
+		Proto   string `json:"proto"`
+		Status  string `json:"status"`
+		Reason  string `json:"reason"`
+		Service struct {
+			Name   string `json:"name"`
+			Banner string `json:"banner"`
+		} `json:"service"`
+	} `json:"ports"`
+
+	Port    int    `json:"port"`
+	Proto   string `json:"proto"`
+	RecType string `json:"rec_type"`
+	Data    struct {
+		Status      string `json:"status"`
+		Reason      string `json:"reason"`
+		ServiceName string `json:"service_name"`
+		Banner      string `json:"banner"`
+	} `json:"data"`
+}
+
+// ParseMasscanJSON reads masscan's JSON (-oJ) or NDJSON (-oD) output. Both
+// write one record per line, so the "finished" records and trailing commas
+// of older versions can be skipped. NDJSON records are either a port status
+// or a banner, as told by their rec_type.
+func ParseMasscanJSON(reader io.Reader, handle HandleHostFunc) error {
+	hosts := newMergedHosts()
+
+	err := parseJSONLines(reader, func(line []byte) error {
+		if bytes.HasPrefix(line, []byte("{finished")) {
+			return nil
+		}
+
+		record := masscanRecord{}
+		err := json.Unmarshal(line, &record)
+		if err != nil {
+			return fmt.Errorf("parsing record %q: %w", line, err)
+		}
+
+		if record.IP == "" {
+			return nil
+		}
+
+		if record.RecType != "" {
+			service := NmapService{Protocol: record.Proto, Portid: record.Port}
+			switch record.RecType {
+			case "status":
+				service.State.State = record.Data.Status
+				service.State.Reason = record.Data.Reason
+			case "banner":
+				service.Service.Name = record.Data.ServiceName
+				service.Service.Banner = record.Data.Banner
+			default:
+				log.Debugf("Ignoring masscan record of type %q.", record.RecType)
+				return nil
+			}
+
+			addMasscanPort(hosts, record.IP, service)
+			return nil
+		}
+
+		for _, port := range record.Ports {
+			service := NmapService{Protocol: port.Proto, Portid: port.Port}
+			service.State.State = port.Status
+			service.State.Reason = port.Reason
+			service.Service.Name = port.Service.Name
+			service.Service.Banner = port.Service.Banner
+
+			addMasscanPort(hosts, record.IP, service)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return hosts.handleAll(handle)
+}
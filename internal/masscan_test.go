@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMasscan(t *testing.T) {
+	for _, filename := range []string{"testdata/masscan.xml", "testdata/masscan.json", "testdata/masscan.ndjson"} {
+		t.Run(filename, func(t *testing.T) {
+			hosts := parseTestdata(t, filename)
+			if len(hosts) != 2 {
+				t.Fatalf("Expected 2 hosts, got %d", len(hosts))
+			}
+
+			host := hosts[0]
+			if host.String() != "10.0.0.5" || host.Status.State != "up" || len(host.Ports.Port) != 2 {
+				t.Fatalf("Wrong host %s (%s) with %d ports", host, host.Status.State, len(host.Ports.Port))
+			}
+
+			ssh, http := host.Ports.Port[0], host.Ports.Port[1]
+			if ssh.Portid != 22 || ssh.State.State != "open" || ssh.Service.Name != "ssh" {
+				t.Errorf("Wrong port %d (%s) %q", ssh.Portid, ssh.State.State, ssh.Service.Name)
+			}
+
+			banner, ok := ssh.Script.Get("banner")
+			if !ok || banner.Output != "ssh: SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.4" {
+				t.Errorf("Wrong banner: %q", banner.Output)
+			}
+
+			banner, _ = http.Script.Get("banner")
+			if http.Service.Name != "http" || banner.Output != "title: Welcome to nginx!\nhttp: HTTP/1.1 200 OK\r\nServer: nginx/1.18.0 (Ubuntu)\r\nContent-Type: text/html" {
+				t.Errorf("Wrong service %q with banner %q", http.Service.Name, banner.Output)
+			}
+
+			https := hosts[1].Ports.Port[0]
+			if https.NameWithTunnel() != "ssl" {
+				t.Errorf("Wrong service %q", https.NameWithTunnel())
+			}
+		})
+	}
+}
+
+func TestParseMasscanStatus(t *testing.T) {
+	input := `{"ip":"10.0.0.5","timestamp":"1760781122","port":53,"proto":"udp","rec_type":"status","data":{"status":"open","reason":"none","ttl":64}}
+{"ip":"10.0.0.5","timestamp":"1760781123","port":25,"proto":"tcp","rec_type":"status","data":{"status":"closed","reason":"rst","ttl":64}}
+`
+
+	var hosts []NmapHost
+	err := ParseScanResults(strings.NewReader(input), func(host NmapHost) error {
+		hosts = append(hosts, host)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error parsing: %v", err)
+	}
+
+	if len(hosts) != 1 {
+		t.Fatalf("Expected 1 host, got %d", len(hosts))
+	}
+
+	if state := hosts[0].PortState("udp", 53); state != "open" {
+		t.Errorf("Wrong state of udp/53: %q", state)
+	}
+
+	if state := hosts[0].PortState("tcp", 25); state != "closed" {
+		t.Errorf("Wrong state of tcp/25: %q", state)
+	}
+}
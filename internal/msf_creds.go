@@ -0,0 +1,198 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	msfOriginServiceType = "Metasploit::Credential::Origin::Service"
+	msfUsernameType      = "Metasploit::Credential::Username"
+	msfBlankUsernameType = "Metasploit::Credential::BlankUsername"
+	msfPasswordType      = "Metasploit::Credential::Password"
+	msfBlankPasswordType = "Metasploit::Credential::BlankPassword"
+	msfLoginSuccessful   = "Successful"
+)
+
+type MsfCredentialOriginService struct {
+	Id             int
+	ServiceId      int
+	ModuleFullName string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (MsfCredentialOriginService) TableName() string {
+	return "metasploit_credential_origin_services"
+}
+
+type MsfCredentialPublic struct {
+	Id       int
+	Username string
+	Type     string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (MsfCredentialPublic) TableName() string {
+	return "metasploit_credential_publics"
+}
+
+type MsfCredentialPrivate struct {
+	Id   int
+	Data string
+	Type string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (MsfCredentialPrivate) TableName() string {
+	return "metasploit_credential_privates"
+}
+
+type MsfCredentialCore struct {
+	Id          int
+	WorkspaceId int
+	OriginId    int
+	OriginType  string
+	PublicId    int
+	PrivateId   int
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (MsfCredentialCore) TableName() string {
+	return "metasploit_credential_cores"
+}
+
+type MsfCredentialLogin struct {
+	Id              int
+	CoreId          int
+	ServiceId       int
+	Status          string
+	LastAttemptedAt time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (MsfCredentialLogin) TableName() string {
+	return "metasploit_credential_logins"
+}
+
+// InsertScriptCredentials stores the valid accounts found by the script as
+// successful logins for the service. The script is recorded as the origin of
+// the credentials.
+func InsertScriptCredentials(db *gorm.DB, msfHost MsfHost, msfService MsfService, script NmapScript) error {
+	credentials := script.Credentials()
+	if len(credentials) == 0 {
+		return nil
+	}
+
+	origin := MsfCredentialOriginService{
+		ServiceId:      msfService.Id,
+		ModuleFullName: fmt.Sprintf("nmap/%s", script.ID),
+	}
+
+	err := db.
+		Where("service_id = ? AND module_full_name = ?", origin.ServiceId, origin.ModuleFullName).
+		FirstOrCreate(&origin).
+		Error
+	if err != nil {
+		return fmt.Errorf("query origin %q: %w", origin.ModuleFullName, err)
+	}
+
+	for _, credential := range credentials {
+		err := insertCredential(db, msfHost.WorkspaceId, msfService, origin, credential)
+		if err != nil {
+			return fmt.Errorf("insert credential for user %q: %w", credential.Username, err)
+		}
+	}
+
+	return nil
+}
+
+func insertCredential(db *gorm.DB, workspaceId int, msfService MsfService, origin MsfCredentialOriginService, credential NmapCredential) error {
+	public := MsfCredentialPublic{Username: credential.Username, Type: msfUsernameType}
+	if credential.Username == "" {
+		public.Type = msfBlankUsernameType
+	}
+
+	err := db.
+		Where("username = ? AND type = ?", public.Username, public.Type).
+		FirstOrCreate(&public).
+		Error
+	if err != nil {
+		return fmt.Errorf("query public: %w", err)
+	}
+
+	private := MsfCredentialPrivate{Data: credential.Password, Type: msfPasswordType}
+	if credential.Password == "" {
+		private.Type = msfBlankPasswordType
+	}
+
+	err = db.
+		Where("data = ? AND type = ?", private.Data, private.Type).
+		FirstOrCreate(&private).
+		Error
+	if err != nil {
+		return fmt.Errorf("query private: %w", err)
+	}
+
+	core := MsfCredentialCore{
+		WorkspaceId: workspaceId,
+		OriginId:    origin.Id,
+		OriginType:  msfOriginServiceType,
+		PublicId:    public.Id,
+		PrivateId:   private.Id,
+	}
+
+	err = db.
+		Where("workspace_id = ? AND public_id = ? AND private_id = ? AND realm_id IS NULL", workspaceId, public.Id, private.Id).
+		FirstOrCreate(&core).
+		Error
+	if err != nil {
+		return fmt.Errorf("query core: %w", err)
+	}
+
+	var login MsfCredentialLogin
+
+	login.CoreId = core.Id
+	login.ServiceId = msfService.Id
+
+	err = db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("core_id = ? AND service_id = ?", core.Id, msfService.Id).
+		FirstOrCreate(&login).
+		Error
+	if err != nil {
+		return fmt.Errorf("query login: %w", err)
+	}
+
+	now := time.Now()
+
+	login.Status = msfLoginSuccessful
+	login.LastAttemptedAt = now
+
+	if login.CreatedAt.IsZero() {
+		login.CreatedAt = now
+	}
+
+	login.UpdatedAt = now
+
+	err = db.Save(&login).Error
+	if err != nil {
+		return fmt.Errorf("save login: %w", err)
+	}
+
+	log.Infof("Inserted/updated credentials for user %q on service %s/%d.", credential.Username, msfService.Proto, msfService.Port)
+
+	return nil
+}
@@ -3,6 +3,7 @@ package internal
 import (
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,6 +14,7 @@ type MsfWorkspace struct {
 	Id          int
 	Name        string
 	Description string
+	Boundary    string
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -27,11 +29,16 @@ type MsfHost struct {
 	WorkspaceId int
 	Address     string
 
-	MAC     string
-	Name    string
-	State   string
-	OSName  string
-	Purpose string
+	MAC      string
+	Name     string
+	State    string
+	OSName   string
+	OSFlavor string
+	OSSp     string
+	OSLang   string
+	OSFamily string
+	Arch     string
+	Purpose  string
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -57,6 +64,24 @@ func (MsfService) TableName() string {
 	return "services"
 }
 
+type MsfNote struct {
+	Id          int
+	WorkspaceId int
+	HostId      int
+	ServiceId   *int
+	Ntype       string
+	Critical    bool
+	Seen        bool
+	Data        string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (MsfNote) TableName() string {
+	return "notes"
+}
+
 func GetWorkspaceId(db *gorm.DB, workspaceName string) (int, error) {
 	var workspace MsfWorkspace
 
@@ -68,22 +93,65 @@ func GetWorkspaceId(db *gorm.DB, workspaceName string) (int, error) {
 	return workspace.Id, nil
 }
 
+// maxWorkspaceFieldLength is the length of the description and boundary
+// columns of the workspaces table.
+const maxWorkspaceFieldLength = 4096
+
+// InsertWorkspace creates a workspace. Its boundary (the hosts that may be
+// targeted from it) is set to the given targets.
+func InsertWorkspace(db *gorm.DB, workspaceName string, description string, targets []string) (int, error) {
+	boundary := strings.Join(targets, " ")
+	if len(boundary) > maxWorkspaceFieldLength {
+		log.Warnf("Boundary of workspace %q is too long, truncating it.", workspaceName)
+		end := strings.LastIndex(boundary[:maxWorkspaceFieldLength+1], " ")
+		if end < 0 {
+			end = maxWorkspaceFieldLength
+		}
+		boundary = boundary[:end]
+	}
+
+	if len(description) > maxWorkspaceFieldLength {
+		description = description[:maxWorkspaceFieldLength]
+	}
+
+	now := time.Now()
+
+	workspace := MsfWorkspace{
+		Name:        workspaceName,
+		Description: description,
+		Boundary:    boundary,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err := db.Create(&workspace).Error
+	if err != nil {
+		return 0, fmt.Errorf("create workspace %q: %w", workspaceName, err)
+	}
+
+	return workspace.Id, nil
+}
+
 func InsertHost(db *gorm.DB, workspaceId int, nmapHost NmapHost) (int, error) {
-	if !nmapHost.HasOpenPorts() {
+	preferredIP := nmapHost.PreferredIPAddress()
+
+	if nmapHost.Status.State != "up" {
+		return 0, updateExistingHostState(db, workspaceId, preferredIP, nmapHost)
+	}
+
+	if !shouldImportHost(nmapHost) {
+		if ReconcileServices {
+			return 0, reconcileExistingHost(db, workspaceId, preferredIP, nmapHost)
+		}
+
 		log.Debugf("Host %s does not have any open ports, skipping.", nmapHost)
 		return 0, nil
 	}
 
 	now := time.Now()
-	openPortCount := 0
-
-	allIPs := nmapHost.AllIPAddresses()
-	var preferredIP net.IP
-	if len(allIPs) > 0 {
-		preferredIP = net.ParseIP(allIPs[0].String())
-	}
+	serviceCount := 0
 
-	db.Transaction(func(tx *gorm.DB) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
 		var msfHost MsfHost
 
 		msfHost.WorkspaceId = workspaceId
@@ -101,60 +169,274 @@ func InsertHost(db *gorm.DB, workspaceId int, nmapHost NmapHost) (int, error) {
 		msfHost.WorkspaceId = workspaceId
 		msfHost.Address = preferredIP.String()
 
-		allMacs := nmapHost.AllMacAddresses()
-		if len(allMacs) > 0 {
-			msfHost.MAC = allMacs[0].String()
-		}
+		updateMsfHost(&msfHost, nmapHost)
 
-		allHostnames := nmapHost.AllHostnames()
-		if len(allHostnames) > 0 {
-			msfHost.Name = allHostnames[0]
+		if msfHost.CreatedAt.IsZero() {
+			msfHost.CreatedAt = now
 		}
 
-		msfHost.State = "alive"
+		msfHost.UpdatedAt = now
 
-		if len(nmapHost.Os.Osmatch) > 0 {
-			msfHost.OSName = nmapHost.Os.Osmatch[0].Name
+		err = tx.Save(&msfHost).Error
+		if err != nil {
+			return fmt.Errorf("save host %v: %w", msfHost, err)
 		}
 
-		if msfHost.Purpose == "" {
-			msfHost.Purpose = "device"
-		}
+		log.Debugf("Inserted/updated host %s.", nmapHost)
 
-		if len(nmapHost.Os.Osclass) > 0 {
-			msfHost.Purpose = nmapHost.Os.Osclass[0].Type
+		err = insertAddressNotes(tx, msfHost, nmapHost)
+		if err != nil {
+			return fmt.Errorf("insert addresses and hostnames for host %s: %w", nmapHost, err)
 		}
 
-		if msfHost.CreatedAt.IsZero() {
-			msfHost.CreatedAt = now
+		err = insertTraceroute(tx, msfHost, nmapHost)
+		if err != nil {
+			return fmt.Errorf("insert traceroute for host %s: %w", nmapHost, err)
 		}
 
-		msfHost.UpdatedAt = now
-
-		err = tx.Save(&msfHost).Error
+		err = insertHostDetails(tx, msfHost, nmapHost)
 		if err != nil {
-			return fmt.Errorf("save host %v: %w", msfHost, err)
+			return fmt.Errorf("insert details for host %s: %w", nmapHost, err)
 		}
 
-		log.Debugf("Inserted/updated host %s.", nmapHost)
+		for _, script := range nmapHost.Hostscript.Script {
+			ntype := fmt.Sprintf("nmap.nse.%s.host", script.ID)
+
+			err := InsertNote(tx, msfHost, nil, ntype, map[string]interface{}{
+				"output": script.FullOutput(),
+			})
+			if err != nil {
+				return fmt.Errorf("insert output of host script %q for host %s: %w", script.ID, nmapHost, err)
+			}
+
+			err = InsertScriptVulns(tx, msfHost, nil, script)
+			if err != nil {
+				return fmt.Errorf("insert vulns of host script %q for host %s: %w", script.ID, nmapHost, err)
+			}
+		}
 
 		for _, port := range nmapHost.Ports.Port {
-			err := InsertService(tx, msfHost.Id, port)
+			if !shouldImportService(port) {
+				continue
+			}
+
+			err := InsertService(tx, msfHost, port)
 			if err != nil {
 				return fmt.Errorf("insert port %s/%d for host %s: %w", port.Protocol, port.Portid, nmapHost, err)
 			}
 
-			openPortCount++
+			serviceCount++
+		}
+
+		if ReconcileServices {
+			err = reconcileServices(tx, msfHost, nmapHost)
+			if err != nil {
+				return fmt.Errorf("reconcile services of host %s: %w", nmapHost, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return serviceCount, nil
+}
+
+// updateMsfHost sets the fields of msfHost from the scan results. Fields that
+// Nmap did not find are left as they are.
+func updateMsfHost(msfHost *MsfHost, nmapHost NmapHost) {
+	allMacs := nmapHost.AllMacAddresses()
+	if len(allMacs) > 0 {
+		msfHost.MAC = allMacs[0].String()
+	}
+
+	hostname := nmapHost.PreferredHostname()
+	if hostname != "" {
+		msfHost.Name = hostname
+	}
+
+	msfHost.State = msfHostState(nmapHost.Status.State)
+
+	if msfHost.Purpose == "" {
+		msfHost.Purpose = "device"
+	}
+
+	if guess, ok := nmapHost.BestOS(MinOSAccuracy); ok {
+		log.Debugf("Best OS match for host %s: %q (%d%%)", nmapHost, guess.Name, guess.Accuracy)
+
+		setIfNotEmpty(&msfHost.OSName, guess.Name)
+		setIfNotEmpty(&msfHost.OSFlavor, guess.Flavor)
+		setIfNotEmpty(&msfHost.OSSp, guess.SP)
+		setIfNotEmpty(&msfHost.OSLang, guess.Lang)
+		setIfNotEmpty(&msfHost.OSFamily, guess.Family)
+		setIfNotEmpty(&msfHost.Arch, guess.Arch)
+		setIfNotEmpty(&msfHost.Purpose, guess.Purpose)
+	}
+}
+
+// insertAddressNotes keeps all addresses and hostnames of hosts that have more
+// than the ones stored on the host itself.
+func insertAddressNotes(db *gorm.DB, msfHost MsfHost, nmapHost NmapHost) error {
+	if len(nmapHost.Address) > 1 {
+		addresses := make([]interface{}, 0, len(nmapHost.Address))
+		for _, address := range nmapHost.Address {
+			entry := map[string]interface{}{
+				"address": address.Addr,
+				"type":    address.Addrtype,
+			}
+			if address.Vendor != "" {
+				entry["vendor"] = address.Vendor
+			}
+			addresses = append(addresses, entry)
+		}
+
+		err := InsertNote(db, msfHost, nil, "host.nmap.addresses", map[string]interface{}{
+			"addresses": addresses,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(nmapHost.Hostnames.Hostname) > 1 {
+		hostnames := make([]interface{}, 0, len(nmapHost.Hostnames.Hostname))
+		for _, hostname := range nmapHost.Hostnames.Hostname {
+			hostnames = append(hostnames, map[string]interface{}{
+				"name": hostname.Name,
+				"type": hostname.Type,
+			})
+		}
+
+		err := InsertNote(db, msfHost, nil, "host.nmap.hostnames", map[string]interface{}{
+			"hostnames": hostnames,
+		})
+		if err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
+
+// findExistingHost looks up a host without creating it.
+func findExistingHost(db *gorm.DB, workspaceId int, address net.IP) (MsfHost, bool, error) {
+	var msfHost MsfHost
+
+	result := db.
+		Where("workspace_id = ? AND address = ?", workspaceId, address.String()).
+		Limit(1).
+		Find(&msfHost)
+	if result.Error != nil {
+		return msfHost, false, fmt.Errorf("query host %v: %w", address, result.Error)
+	}
+
+	return msfHost, result.RowsAffected > 0, nil
+}
+
+// updateExistingHostState records that a host is no longer up, if it is
+// already known to Metasploit.
+func updateExistingHostState(db *gorm.DB, workspaceId int, preferredIP net.IP, nmapHost NmapHost) error {
+	msfHost, found, err := findExistingHost(db, workspaceId, preferredIP)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		log.Debugf("Host %s is %s and unknown, skipping.", nmapHost, nmapHost.Status.State)
+		return nil
+	}
+
+	msfHost.State = msfHostState(nmapHost.Status.State)
+	msfHost.UpdatedAt = time.Now()
+
+	err = db.Save(&msfHost).Error
+	if err != nil {
+		return fmt.Errorf("save host %v: %w", msfHost, err)
+	}
 
+	log.Infof("Host %s is %s, marked as %s.", nmapHost, nmapHost.Status.State, msfHost.State)
+
+	return nil
+}
+
+// reconcileExistingHost reconciles the services of a host without open
+// ports, if it is already known to Metasploit.
+func reconcileExistingHost(db *gorm.DB, workspaceId int, preferredIP net.IP, nmapHost NmapHost) error {
+	msfHost, found, err := findExistingHost(db, workspaceId, preferredIP)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		log.Debugf("Host %s does not have any open ports and is unknown, skipping.", nmapHost)
 		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return reconcileServices(tx, msfHost, nmapHost)
 	})
+}
+
+// reconcileServices marks open services of the host as closed (or filtered)
+// if their port was scanned, but not found open.
+func reconcileServices(db *gorm.DB, msfHost MsfHost, nmapHost NmapHost) error {
+	var msfServices []MsfService
+
+	err := db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("host_id = ? AND state = ?", msfHost.Id, "open").
+		Find(&msfServices).
+		Error
+	if err != nil {
+		return fmt.Errorf("query open services: %w", err)
+	}
+
+	now := time.Now()
+
+	for _, msfService := range reconciledServices(msfServices, nmapHost) {
+		msfService.UpdatedAt = now
+
+		err = db.Save(&msfService).Error
+		if err != nil {
+			return fmt.Errorf("save service %v: %w", msfService, err)
+		}
+
+		log.Infof("Service %s/%d of host %s is no longer open, marked as %s.", msfService.Proto, msfService.Port, nmapHost, msfService.State)
+	}
+
+	return nil
+}
 
-	return openPortCount, nil
+// reconciledServices returns those of the open services whose port was
+// scanned, but not found open, with their new state.
+func reconciledServices(msfServices []MsfService, nmapHost NmapHost) []MsfService {
+	reconciled := make([]MsfService, 0)
+
+	for _, msfService := range msfServices {
+		if !nmapHost.ScannedPorts(msfService.Proto).Contains(msfService.Port) {
+			continue
+		}
+
+		state := nmapHost.PortState(msfService.Proto, msfService.Port)
+		if state == "open" {
+			continue
+		}
+
+		if state == "" {
+			state = "closed"
+		}
+
+		msfService.State = msfServiceState(state)
+		reconciled = append(reconciled, msfService)
+	}
+
+	return reconciled
 }
 
-func InsertService(db *gorm.DB, hostId int, service NmapService) error {
-	if service.State.State != "open" {
+func InsertService(db *gorm.DB, msfHost MsfHost, service NmapService) error {
+	if !shouldImportService(service) {
 		return nil
 	}
 
@@ -164,7 +446,7 @@ func InsertService(db *gorm.DB, hostId int, service NmapService) error {
 		Clauses(clause.Locking{Strength: "UPDATE"}).
 		Where(
 			"host_id = ? AND proto = ? AND port = ?",
-			hostId,
+			msfHost.Id,
 			service.Protocol,
 			service.Portid,
 		).
@@ -177,10 +459,81 @@ func InsertService(db *gorm.DB, hostId int, service NmapService) error {
 
 	now := time.Now()
 
-	msfService.HostId = hostId
+	msfService.HostId = msfHost.Id
 	msfService.Proto = service.Protocol
 	msfService.Port = service.Portid
-	msfService.State = service.State.State
+
+	updateMsfService(&msfService, service)
+
+	if msfService.CreatedAt.IsZero() {
+		msfService.CreatedAt = now
+	}
+
+	msfService.UpdatedAt = now
+
+	err = db.Save(&msfService).Error
+	if err != nil {
+		return fmt.Errorf("save service %v: %w", msfService, err)
+	}
+
+	log.Debugf("Inserted/updated service %s.", service)
+
+	fingerprint := make(map[string]interface{})
+	for key, value := range map[string]string{
+		"product":   service.Service.Product,
+		"version":   service.Service.Version,
+		"extrainfo": service.Service.Extrainfo,
+		"ostype":    service.Service.Ostype,
+		"hostname":  service.Service.Hostname,
+	} {
+		if value != "" {
+			fingerprint[key] = value
+		}
+	}
+	if len(service.Service.Cpe) > 0 {
+		fingerprint["cpe"] = service.Service.Cpe
+	}
+
+	if len(fingerprint) > 0 {
+		err = InsertNote(db, msfHost, &msfService.Id, "service.nmap.fingerprint", fingerprint)
+		if err != nil {
+			return fmt.Errorf("insert fingerprint: %w", err)
+		}
+	}
+
+	for _, script := range service.Script {
+		ntype := fmt.Sprintf("nmap.nse.%s.%s.%d", script.ID, service.Protocol, service.Portid)
+
+		err = InsertNote(db, msfHost, &msfService.Id, ntype, map[string]interface{}{
+			"output": script.FullOutput(),
+		})
+		if err != nil {
+			return fmt.Errorf("insert output of script %q: %w", script.ID, err)
+		}
+
+		err = InsertScriptVulns(db, msfHost, &msfService.Id, script)
+		if err != nil {
+			return fmt.Errorf("insert vulns of script %q: %w", script.ID, err)
+		}
+
+		err = InsertScriptCredentials(db, msfHost, msfService, script)
+		if err != nil {
+			return fmt.Errorf("insert credentials of script %q: %w", script.ID, err)
+		}
+	}
+
+	err = InsertWebSite(db, msfHost, msfService, service)
+	if err != nil {
+		return fmt.Errorf("insert web site: %w", err)
+	}
+
+	return nil
+}
+
+// updateMsfService sets the state, name and info of msfService from the scan
+// results. Name and info are only overwritten if Nmap identified the service.
+func updateMsfService(msfService *MsfService, service NmapService) {
+	msfService.State = msfServiceState(service.State.State)
 
 	name := service.Service.Name
 	if service.Service.Tunnel != "" {
@@ -190,26 +543,103 @@ func InsertService(db *gorm.DB, hostId int, service NmapService) error {
 		msfService.Name = name
 	}
 
-	if service.Service.Product != "" {
-		msfService.Info = service.Service.Product
+	info := service.Info()
+	if info != "" {
+		msfService.Info = info
+	}
+}
 
-		if service.Service.Version != "" {
-			msfService.Info = fmt.Sprintf("%s %s", msfService.Info, service.Service.Version)
-		}
+// InsertNote creates or updates the note of the given type attached to the
+// host and (if serviceId is not nil) the service. Data is serialized the same
+// way Metasploit does.
+func InsertNote(db *gorm.DB, msfHost MsfHost, serviceId *int, ntype string, data map[string]interface{}) error {
+	serialized, err := encodeRubyBase64(data)
+	if err != nil {
+		return fmt.Errorf("serialize data of note %q: %w", ntype, err)
 	}
 
-	if msfService.CreatedAt.IsZero() {
-		msfService.CreatedAt = now
+	var msfNote MsfNote
+
+	msfNote.WorkspaceId = msfHost.WorkspaceId
+	msfNote.HostId = msfHost.Id
+	msfNote.ServiceId = serviceId
+	msfNote.Ntype = ntype
+
+	err = whereServiceId(db.Clauses(clause.Locking{Strength: "UPDATE"}), serviceId).
+		Where("workspace_id = ? AND host_id = ? AND ntype = ?", msfHost.WorkspaceId, msfHost.Id, ntype).
+		FirstOrCreate(&msfNote).
+		Error
+	if err != nil {
+		return fmt.Errorf("query note %q: %w", ntype, err)
 	}
 
-	msfService.UpdatedAt = now
+	now := time.Now()
 
-	err = db.Save(&msfService).Error
+	msfNote.Data = serialized
+
+	if msfNote.CreatedAt.IsZero() {
+		msfNote.CreatedAt = now
+	}
+
+	msfNote.UpdatedAt = now
+
+	err = db.Save(&msfNote).Error
 	if err != nil {
-		return fmt.Errorf("save service %v: %w", msfService, err)
+		return fmt.Errorf("save note %q: %w", ntype, err)
 	}
 
-	log.Debugf("Inserted/updated service %s.", service)
+	log.Debugf("Inserted/updated note %q.", ntype)
 
 	return nil
 }
+
+// shouldImportHost returns true for hosts that are up and have open ports, or
+// any services to import if IncludeClosedPorts is set.
+func shouldImportHost(nmapHost NmapHost) bool {
+	return nmapHost.HasOpenPorts() || (IncludeClosedPorts && nmapHost.Status.State == "up")
+}
+
+func shouldImportService(service NmapService) bool {
+	return service.State.State == "open" || IncludeClosedPorts
+}
+
+// msfHostState maps Nmap's host states onto the host states allowed by
+// Metasploit (alive, down and unknown).
+func msfHostState(state string) string {
+	switch state {
+	case "up":
+		return "alive"
+	case "down":
+		return "down"
+	default:
+		return "unknown"
+	}
+}
+
+// msfServiceState maps Nmap's port states onto the service states allowed by
+// Metasploit (open, closed, filtered and unknown).
+func msfServiceState(state string) string {
+	switch state {
+	case "open", "closed", "filtered":
+		return state
+	case "open|filtered", "closed|filtered":
+		return "filtered"
+	default:
+		return "unknown"
+	}
+}
+
+// whereServiceId restricts the query to rows attached to the given service,
+// or to rows not attached to any service if serviceId is nil.
+func whereServiceId(db *gorm.DB, serviceId *int) *gorm.DB {
+	if serviceId == nil {
+		return db.Where("service_id IS NULL")
+	}
+	return db.Where("service_id = ?", *serviceId)
+}
+
+func setIfNotEmpty(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
@@ -0,0 +1,49 @@
+package internal
+
+import "testing"
+
+func TestMsfServiceState(t *testing.T) {
+	cases := map[string]string{
+		"open":            "open",
+		"closed":          "closed",
+		"filtered":        "filtered",
+		"open|filtered":   "filtered",
+		"closed|filtered": "filtered",
+		"unfiltered":      "unknown",
+		"":                "unknown",
+	}
+
+	for state, expected := range cases {
+		if got := msfServiceState(state); got != expected {
+			t.Errorf("%q: got %q, expected %q", state, got, expected)
+		}
+	}
+}
+
+func TestShouldImportService(t *testing.T) {
+	defer func(old bool) { IncludeClosedPorts = old }(IncludeClosedPorts)
+
+	cases := []struct {
+		state         string
+		includeClosed bool
+		expected      bool
+	}{
+		{"open", false, true},
+		{"closed", false, false},
+		{"filtered", false, false},
+		{"open", true, true},
+		{"closed", true, true},
+		{"open|filtered", true, true},
+	}
+
+	for _, c := range cases {
+		IncludeClosedPorts = c.includeClosed
+
+		var service NmapService
+		service.State.State = c.state
+
+		if got := shouldImportService(service); got != c.expected {
+			t.Errorf("%q (include closed: %v): got %v, expected %v", c.state, c.includeClosed, got, c.expected)
+		}
+	}
+}
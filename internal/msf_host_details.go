@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// hostDetail is the data of one host note.
+type hostDetail struct {
+	ntype string
+	data  map[string]interface{}
+}
+
+// insertHostDetails stores uptime, distance, sequence analysis and the OS
+// fingerprint of the host as notes of type "host.nmap.*".
+func insertHostDetails(db *gorm.DB, msfHost MsfHost, nmapHost NmapHost) error {
+	for _, detail := range hostDetails(nmapHost) {
+		err := InsertNote(db, msfHost, nil, detail.ntype, detail.data)
+		if err != nil {
+			return fmt.Errorf("insert %s: %w", detail.ntype, err)
+		}
+	}
+
+	return nil
+}
+
+// hostDetails returns the notes insertHostDetails stores, in a fixed order.
+// Details Nmap did not report are left out.
+func hostDetails(nmapHost NmapHost) []hostDetail {
+	details := []hostDetail{
+		{"host.nmap.uptime", noteData(
+			"seconds", nmapHost.Uptime.Seconds,
+			"lastboot", nmapHost.Uptime.Lastboot,
+		)},
+		{"host.nmap.distance", noteData(
+			"hops", nmapHost.Distance.Value,
+		)},
+		{"host.nmap.tcpsequence", noteData(
+			"index", nmapHost.Tcpsequence.Index,
+			"class", nmapHost.Tcpsequence.Class,
+			"difficulty", nmapHost.Tcpsequence.Difficulty,
+			"values", nmapHost.Tcpsequence.Values,
+		)},
+		{"host.nmap.ipidsequence", noteData(
+			"class", nmapHost.Ipidsequence.Class,
+			"values", nmapHost.Ipidsequence.Values,
+		)},
+		{"host.nmap.tcptssequence", noteData(
+			"class", nmapHost.Tcptssequence.Class,
+			"values", nmapHost.Tcptssequence.Values,
+		)},
+		{"host.nmap.osfingerprint", noteData(
+			"fingerprint", nmapHost.Os.Osfingerprint.Fingerprint,
+		)},
+	}
+
+	reported := make([]hostDetail, 0, len(details))
+	for _, detail := range details {
+		if len(detail.data) > 0 {
+			reported = append(reported, detail)
+		}
+	}
+
+	return reported
+}
+
+// noteData builds note data from alternating keys and values, skipping empty
+// values. Values that are integers are stored as such.
+func noteData(keysAndValues ...string) map[string]interface{} {
+	data := make(map[string]interface{})
+
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key, value := keysAndValues[i], keysAndValues[i+1]
+		if value == "" {
+			continue
+		}
+
+		if n, err := strconv.Atoi(value); err == nil && n >= rubyFixnumMin && n <= rubyFixnumMax {
+			data[key] = n
+		} else {
+			data[key] = value
+		}
+	}
+
+	return data
+}
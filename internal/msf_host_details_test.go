@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestHostDetails(t *testing.T) {
+	data := `<host>
+<uptime seconds="86400" lastboot="Fri Oct 17 10:00:00 2026"/>
+<distance value="4"/>
+<ipidsequence class="All zeros" values="0,0,0,0,0,0"/>
+</host>`
+
+	var host NmapHost
+	err := xml.Unmarshal([]byte(data), &host)
+	if err != nil {
+		t.Fatalf("Error parsing host: %v", err)
+	}
+
+	expected := []hostDetail{
+		{"host.nmap.uptime", map[string]interface{}{"seconds": 86400, "lastboot": "Fri Oct 17 10:00:00 2026"}},
+		{"host.nmap.distance", map[string]interface{}{"hops": 4}},
+		{"host.nmap.ipidsequence", map[string]interface{}{"class": "All zeros", "values": "0,0,0,0,0,0"}},
+	}
+
+	details := hostDetails(host)
+	if !reflect.DeepEqual(details, expected) {
+		t.Errorf("Got %v, expected %v", details, expected)
+	}
+}
+
+func TestNoteData(t *testing.T) {
+	cases := []struct {
+		keysAndValues []string
+		expected      map[string]interface{}
+	}{
+		{[]string{"hops", "4"}, map[string]interface{}{"hops": 4}},
+		{[]string{"class", "", "index", "262"}, map[string]interface{}{"index": 262}},
+		{[]string{"values", "2B9E0A5C"}, map[string]interface{}{"values": "2B9E0A5C"}},
+		{[]string{"seconds", "4294967296"}, map[string]interface{}{"seconds": "4294967296"}},
+		{[]string{"dangling"}, map[string]interface{}{}},
+	}
+
+	for _, c := range cases {
+		data := noteData(c.keysAndValues...)
+		if !reflect.DeepEqual(data, c.expected) {
+			t.Errorf("%q: got %v, expected %v", c.keysAndValues, data, c.expected)
+		}
+	}
+}
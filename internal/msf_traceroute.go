@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// insertTraceroute stores the traceroute of the host as a note and, if
+// RegisterRouters is enabled, creates hosts for the intermediate hops.
+func insertTraceroute(db *gorm.DB, msfHost MsfHost, nmapHost NmapHost) error {
+	if len(nmapHost.Trace.Hop) == 0 {
+		return nil
+	}
+
+	err := InsertNote(db, msfHost, nil, "host.nmap.traceroute", tracerouteData(nmapHost))
+	if err != nil {
+		return fmt.Errorf("insert traceroute: %w", err)
+	}
+
+	if !RegisterRouters {
+		return nil
+	}
+
+	for _, hop := range routerHops(nmapHost, msfHost.Address) {
+		ip := net.ParseIP(hop.Ipaddr)
+
+		err := insertRouter(db, msfHost.WorkspaceId, ip, hop.Host)
+		if err != nil {
+			return fmt.Errorf("insert router %s: %w", ip, err)
+		}
+	}
+
+	return nil
+}
+
+// tracerouteData converts the traceroute of the host into note data.
+func tracerouteData(nmapHost NmapHost) map[string]interface{} {
+	trace := nmapHost.Trace
+
+	hops := make([]interface{}, 0, len(trace.Hop))
+	for _, hop := range trace.Hop {
+		entry := map[string]interface{}{
+			"address": hop.Ipaddr,
+			"rtt":     hop.Rtt,
+		}
+
+		ttl, err := strconv.Atoi(hop.Ttl)
+		if err == nil {
+			entry["ttl"] = ttl
+		}
+
+		if hop.Host != "" {
+			entry["name"] = hop.Host
+		}
+
+		hops = append(hops, entry)
+	}
+
+	data := map[string]interface{}{
+		"hops": hops,
+	}
+	if trace.Proto != "" {
+		data["proto"] = trace.Proto
+	}
+	if port, err := strconv.Atoi(trace.Port); err == nil {
+		data["port"] = port
+	}
+
+	return data
+}
+
+// routerHops returns the hops of the traceroute that are routers, i.e. all
+// hops with a valid address except the host (at address) itself.
+func routerHops(nmapHost NmapHost, address string) []NmapHop {
+	routers := make([]NmapHop, 0)
+
+	hops := nmapHost.Trace.Hop
+	if len(hops) == 0 {
+		return routers
+	}
+
+	// the last hop is the host itself
+	for _, hop := range hops[:len(hops)-1] {
+		ip := net.ParseIP(hop.Ipaddr)
+		if ip == nil || ip.String() == address {
+			continue
+		}
+
+		routers = append(routers, hop)
+	}
+
+	return routers
+}
+
+func insertRouter(db *gorm.DB, workspaceId int, ip net.IP, name string) error {
+	var msfHost MsfHost
+
+	msfHost.WorkspaceId = workspaceId
+	msfHost.Address = ip.String()
+
+	err := db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("workspace_id = ? AND address = ?", msfHost.WorkspaceId, msfHost.Address).
+		FirstOrCreate(&msfHost).
+		Error
+	if err != nil {
+		return fmt.Errorf("query host %v: %w", ip, err)
+	}
+
+	now := time.Now()
+
+	updateRouter(&msfHost, name)
+
+	if msfHost.CreatedAt.IsZero() {
+		msfHost.CreatedAt = now
+	}
+
+	msfHost.UpdatedAt = now
+
+	err = db.Save(&msfHost).Error
+	if err != nil {
+		return fmt.Errorf("save host %v: %w", msfHost, err)
+	}
+
+	log.Debugf("Inserted/updated router %s.", ip)
+
+	return nil
+}
+
+// updateRouter marks the host as a router, without overriding what is known
+// from scanning the router itself.
+func updateRouter(msfHost *MsfHost, name string) {
+	if msfHost.Name == "" {
+		msfHost.Name = name
+	}
+
+	if msfHost.Purpose == "" || msfHost.Purpose == "device" {
+		msfHost.Purpose = "router"
+	}
+
+	if msfHost.State == "" {
+		msfHost.State = msfHostState("up")
+	}
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestTraceroute(t *testing.T) {
+	data := `<host>
+<trace port="80" proto="tcp">
+<hop ttl="1" ipaddr="192.168.1.1" rtt="0.52" host="gateway.lan"/>
+<hop ttl="2" ipaddr="" rtt="--"/>
+<hop ttl="3" ipaddr="10.0.0.1" rtt="8.10"/>
+<hop ttl="4" ipaddr="45.33.32.156" rtt="21.33" host="scanme.nmap.org"/>
+</trace>
+</host>`
+
+	var host NmapHost
+	err := xml.Unmarshal([]byte(data), &host)
+	if err != nil {
+		t.Fatalf("Error parsing host: %v", err)
+	}
+
+	note := tracerouteData(host)
+	if note["proto"] != "tcp" || note["port"] != 80 {
+		t.Errorf("Wrong proto %v or port %v", note["proto"], note["port"])
+	}
+
+	hops := note["hops"].([]interface{})
+	expected := map[string]interface{}{"address": "192.168.1.1", "rtt": "0.52", "ttl": 1, "name": "gateway.lan"}
+	if len(hops) != 4 || !reflect.DeepEqual(hops[0], expected) {
+		t.Errorf("Wrong hops: %v", hops)
+	}
+
+	cases := []struct {
+		address  string
+		expected []string
+	}{
+		{"45.33.32.156", []string{"192.168.1.1", "10.0.0.1"}},
+		{"10.0.0.1", []string{"192.168.1.1"}},
+	}
+
+	for _, c := range cases {
+		routers := make([]string, 0)
+		for _, hop := range routerHops(host, c.address) {
+			routers = append(routers, hop.Ipaddr)
+		}
+
+		if !reflect.DeepEqual(routers, c.expected) {
+			t.Errorf("%s: got routers %v, expected %v", c.address, routers, c.expected)
+		}
+	}
+
+	if routers := routerHops(NmapHost{}, "45.33.32.156"); len(routers) != 0 {
+		t.Errorf("Got routers %v without traceroute", routers)
+	}
+}
+
+func TestUpdateRouter(t *testing.T) {
+	cases := []struct {
+		existing MsfHost
+		expected MsfHost
+	}{
+		{MsfHost{}, MsfHost{Name: "gateway.lan", Purpose: "router", State: "alive"}},
+		{MsfHost{Purpose: "device", State: "down"}, MsfHost{Name: "gateway.lan", Purpose: "router", State: "down"}},
+		{MsfHost{Name: "fw01", Purpose: "firewall", State: "alive"}, MsfHost{Name: "fw01", Purpose: "firewall", State: "alive"}},
+	}
+
+	for _, c := range cases {
+		updated := c.existing
+		updateRouter(&updated, "gateway.lan")
+
+		if updated != c.expected {
+			t.Errorf("%+v: got %+v, expected %+v", c.existing, updated, c.expected)
+		}
+	}
+}
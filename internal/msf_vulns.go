@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MsfVuln struct {
+	Id        int
+	HostId    int
+	ServiceId *int
+	Name      string
+	Info      string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (MsfVuln) TableName() string {
+	return "vulns"
+}
+
+type MsfRef struct {
+	Id   int
+	Name string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (MsfRef) TableName() string {
+	return "refs"
+}
+
+type MsfVulnRef struct {
+	Id     int
+	VulnId int
+	RefId  int
+}
+
+func (MsfVulnRef) TableName() string {
+	return "vulns_refs"
+}
+
+// InsertScriptVulns creates vulns (and their refs) for all vulnerabilities
+// found in the script's structured output.
+func InsertScriptVulns(db *gorm.DB, msfHost MsfHost, serviceId *int, script NmapScript) error {
+	for _, vuln := range script.Vulns() {
+		err := InsertVuln(db, msfHost, serviceId, vuln)
+		if err != nil {
+			return fmt.Errorf("insert vuln %q: %w", vuln.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func InsertVuln(db *gorm.DB, msfHost MsfHost, serviceId *int, vuln NmapVuln) error {
+	var msfVuln MsfVuln
+
+	msfVuln.HostId = msfHost.Id
+	msfVuln.ServiceId = serviceId
+	msfVuln.Name = vuln.Name
+
+	err := whereServiceId(db.Clauses(clause.Locking{Strength: "UPDATE"}), serviceId).
+		Where("host_id = ? AND name = ?", msfHost.Id, vuln.Name).
+		FirstOrCreate(&msfVuln).
+		Error
+	if err != nil {
+		return fmt.Errorf("query vuln %q: %w", vuln.Name, err)
+	}
+
+	now := time.Now()
+
+	msfVuln.Info = vuln.Info
+
+	if msfVuln.CreatedAt.IsZero() {
+		msfVuln.CreatedAt = now
+	}
+
+	msfVuln.UpdatedAt = now
+
+	err = db.Save(&msfVuln).Error
+	if err != nil {
+		return fmt.Errorf("save vuln %q: %w", vuln.Name, err)
+	}
+
+	for _, refName := range vuln.Refs {
+		msfRef := MsfRef{Name: refName}
+
+		err = db.
+			Where("name = ?", refName).
+			FirstOrCreate(&msfRef).
+			Error
+		if err != nil {
+			return fmt.Errorf("query ref %q: %w", refName, err)
+		}
+
+		msfVulnRef := MsfVulnRef{VulnId: msfVuln.Id, RefId: msfRef.Id}
+
+		err = db.
+			Where("vuln_id = ? AND ref_id = ?", msfVuln.Id, msfRef.Id).
+			FirstOrCreate(&msfVulnRef).
+			Error
+		if err != nil {
+			return fmt.Errorf("link ref %q: %w", refName, err)
+		}
+	}
+
+	log.Debugf("Inserted/updated vuln %q with %d refs.", vuln.Name, len(vuln.Refs))
+
+	return nil
+}
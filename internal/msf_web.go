@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MsfWebSite struct {
+	Id        int
+	ServiceId int
+	Vhost     string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (MsfWebSite) TableName() string {
+	return "web_sites"
+}
+
+type MsfWebPage struct {
+	Id        int
+	WebSiteId int
+	Path      string
+	Query     string
+	Code      int
+	Cookie    string
+	Ctype     string
+	Location  string
+	Headers   string
+	Body      string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (MsfWebPage) TableName() string {
+	return "web_pages"
+}
+
+// InsertWebSite creates the web site for an HTTP service and the pages found
+// by HTTP scripts. The virtual host is the host's name, or its address if it
+// does not have one.
+func InsertWebSite(db *gorm.DB, msfHost MsfHost, msfService MsfService, service NmapService) error {
+	if !service.IsHTTP() || service.State.State != "open" {
+		return nil
+	}
+
+	vhost := msfHost.Name
+	if vhost == "" {
+		vhost = msfHost.Address
+	}
+
+	var msfWebSite MsfWebSite
+
+	msfWebSite.ServiceId = msfService.Id
+	msfWebSite.Vhost = vhost
+
+	err := db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("service_id = ? AND vhost = ?", msfService.Id, vhost).
+		FirstOrCreate(&msfWebSite).
+		Error
+	if err != nil {
+		return fmt.Errorf("query web site %q: %w", vhost, err)
+	}
+
+	now := time.Now()
+
+	if msfWebSite.CreatedAt.IsZero() {
+		msfWebSite.CreatedAt = now
+	}
+
+	msfWebSite.UpdatedAt = now
+
+	err = db.Save(&msfWebSite).Error
+	if err != nil {
+		return fmt.Errorf("save web site %q: %w", vhost, err)
+	}
+
+	log.Debugf("Inserted/updated web site %q on %s.", vhost, service)
+
+	for _, page := range service.WebPages() {
+		err := insertWebPage(db, msfWebSite, page)
+		if err != nil {
+			return fmt.Errorf("insert web page %q: %w", page.Path, err)
+		}
+	}
+
+	return nil
+}
+
+func insertWebPage(db *gorm.DB, msfWebSite MsfWebSite, page NmapWebPage) error {
+	path, query := page.SplitPath()
+
+	var msfWebPage MsfWebPage
+
+	msfWebPage.WebSiteId = msfWebSite.Id
+	msfWebPage.Path = path
+	msfWebPage.Query = query
+	msfWebPage.Code = page.Code
+
+	err := db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("web_site_id = ? AND path = ? AND query = ?", msfWebSite.Id, path, query).
+		FirstOrCreate(&msfWebPage).
+		Error
+	if err != nil {
+		return fmt.Errorf("query web page: %w", err)
+	}
+
+	now := time.Now()
+
+	msfWebPage.Code = page.Code
+	setIfNotEmpty(&msfWebPage.Ctype, page.Ctype)
+	setIfNotEmpty(&msfWebPage.Cookie, page.Cookie)
+	setIfNotEmpty(&msfWebPage.Location, page.Location)
+	setIfNotEmpty(&msfWebPage.Body, page.Body)
+
+	if len(page.Headers) > 0 {
+		headers := make(map[string]interface{}, len(page.Headers))
+		for name, values := range page.Headers {
+			headers[name] = values
+		}
+
+		msfWebPage.Headers, err = encodeRubyBase64(headers)
+		if err != nil {
+			return fmt.Errorf("serialize headers: %w", err)
+		}
+	}
+
+	if msfWebPage.CreatedAt.IsZero() {
+		msfWebPage.CreatedAt = now
+	}
+
+	msfWebPage.UpdatedAt = now
+
+	err = db.Save(&msfWebPage).Error
+	if err != nil {
+		return fmt.Errorf("save web page: %w", err)
+	}
+
+	log.Debugf("Inserted/updated web page %q of web site %q.", page.Path, msfWebSite.Vhost)
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// nmapOptionsWithValue are the options of Nmap that take their value as the
+// following argument.
+var nmapOptionsWithValue = map[string]bool{
+	"-iL": true, "-iR": true, "--exclude": true, "--excludefile": true,
+	"--dns-servers": true, "--scanflags": true, "-sI": true, "-b": true,
+	"-p": true, "--exclude-ports": true, "--top-ports": true, "--port-ratio": true,
+	"--version-intensity": true, "--script": true, "--script-args": true,
+	"--script-args-file": true, "--script-help": true, "--script-timeout": true,
+	"--max-os-tries": true, "--min-hostgroup": true, "--max-hostgroup": true,
+	"--min-parallelism": true, "--max-parallelism": true,
+	"--min-rtt-timeout": true, "--max-rtt-timeout": true, "--initial-rtt-timeout": true,
+	"--max-retries": true, "--host-timeout": true, "--scan-delay": true,
+	"--max-scan-delay": true, "--min-rate": true, "--max-rate": true,
+	"--mtu": true, "-D": true, "-S": true, "-e": true, "-g": true, "--source-port": true,
+	"--proxies": true, "--data": true, "--data-string": true, "--data-length": true,
+	"--ip-options": true, "--ttl": true, "--spoof-mac": true,
+	"-oN": true, "-oX": true, "-oS": true, "-oG": true, "-oA": true,
+	"--stylesheet": true, "--datadir": true, "--servicedb": true, "--versiondb": true,
+	"--resume": true, "--stats-every": true,
+}
+
+// NmapTargets returns the target specifications (host names, addresses,
+// networks and ranges) from Nmap's command-line arguments, excluding the
+// program name. Targets from -iL input files are included if the file can
+// be read.
+func NmapTargets(args []string) []string {
+	targets := make([]string, 0)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if !strings.HasPrefix(arg, "-") {
+			targets = append(targets, arg)
+			continue
+		}
+
+		if !nmapOptionsWithValue[arg] || i+1 >= len(args) {
+			continue
+		}
+
+		i++
+		if arg == "-iL" {
+			targets = append(targets, readTargetsFile(args[i])...)
+		}
+	}
+
+	return targets
+}
+
+func readTargetsFile(filename string) []string {
+	file, err := os.Open(filename)
+	if err != nil {
+		log.Debugf("Cannot read targets from %q: %v", filename, err)
+		return nil
+	}
+	defer file.Close()
+
+	targets := make([]string, 0)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		targets = append(targets, strings.Fields(line)...)
+	}
+
+	return targets
+}
@@ -0,0 +1,17 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNmapTargets(t *testing.T) {
+	args := []string{"-sV", "-p", "22,80", "--script=vuln", "-oX", "out.xml", "scanme.nmap.org", "-T4", "10.0.0.0/24", "--exclude", "10.0.0.1"}
+
+	targets := NmapTargets(args)
+
+	expected := []string{"scanme.nmap.org", "10.0.0.0/24"}
+	if strings.Join(targets, " ") != strings.Join(expected, " ") {
+		t.Errorf("Got targets %v, expected %v", targets, expected)
+	}
+}
@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// grepableHeaderRegexp matches the first comment line of grepable output,
+// e.g. "# Nmap 7.94 scan initiated Sat Oct 18 10:00:00 2026 as: nmap -oG ...".
+var grepableHeaderRegexp = regexp.MustCompile(`^# Nmap (\S+) scan initiated .*? as: (.*)$`)
+
+// grepableScannedRegexp matches one protocol of the "# Ports scanned:" line
+// that Nmap writes in verbose mode, e.g. "TCP(1000;1,3-4,6-7)".
+var grepableScannedRegexp = regexp.MustCompile(`([A-Z]+)\((\d+);([^)]*)\)`)
+
+// grepableHostRegexp matches the "Host:" field, e.g.
+// "Host: 45.33.32.156 (scanme.nmap.org)".
+var grepableHostRegexp = regexp.MustCompile(`^Host: (\S+) \(([^)]*)\)$`)
+
+// sniffNmapGrepable looks for a "Host:" line, as Nmap's normal output (-oN)
+// starts with the same "# Nmap" comment.
+func sniffNmapGrepable(prefix []byte) bool {
+	for _, line := range bytes.Split(prefix, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("Host: ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNmapGrepable reads Nmap's grepable output (-oG) and calls handle for
+// each host. The host's lines (Status, Ports) are merged into one NmapHost.
+// As grepable output lacks most details, the version columns of a port end
+// up in the service's product.
+func ParseNmapGrepable(reader io.Reader, handle HandleHostFunc) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	scaninfo := make([]NmapScaninfo, 0)
+
+	var host *NmapHost
+	seenHost := false
+	flush := func() error {
+		if host == nil {
+			return nil
+		}
+
+		host.Scaninfo = scaninfo
+		err := handle(*host)
+		host = nil
+		if err != nil {
+			return fmt.Errorf("handling host: %w", err)
+		}
+		return nil
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.HasPrefix(line, "#") {
+			if match := grepableHeaderRegexp.FindStringSubmatch(line); match != nil {
+				checkVersion(match[1])
+			} else if rest, ok := strings.CutPrefix(line, "# Ports scanned: "); ok {
+				scaninfo = parseGrepableScanned(rest)
+			}
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+
+		match := grepableHostRegexp.FindStringSubmatch(fields[0])
+		if match == nil {
+			if strings.TrimSpace(line) != "" {
+				log.Debugf("Ignoring unknown line in grepable output: %q", line)
+			}
+			continue
+		}
+
+		address, hostname := match[1], match[2]
+		seenHost = true
+
+		if host == nil || host.Address[0].Addr != address {
+			err := flush()
+			if err != nil {
+				return err
+			}
+
+			host = newGrepableHost(address, hostname)
+		}
+
+		for _, field := range fields[1:] {
+			name, value, ok := strings.Cut(field, ": ")
+			if !ok {
+				continue
+			}
+
+			switch name {
+			case "Status":
+				host.Status.State = strings.ToLower(value)
+			case "Ports":
+				// only hosts that are up have ports
+				host.Status.State = "up"
+
+				for _, entry := range splitGrepablePorts(value) {
+					service, err := parseGrepablePort(entry)
+					if err != nil {
+						log.Warnf("Ignoring invalid port %q of host %s: %v", entry, address, err)
+						continue
+					}
+					host.Ports.Port = append(host.Ports.Port, service)
+				}
+			case "Ignored State":
+				// e.g. "closed (996)"
+				state, count, _ := strings.Cut(value, " ")
+				host.Ports.Extraports = append(host.Ports.Extraports, NmapExtraports{
+					State: state,
+					Count: strings.Trim(count, "()"),
+				})
+			case "OS":
+				// only perfect matches are written to grepable output
+				for _, name := range strings.Split(value, " | ") {
+					host.Os.Osmatch = append(host.Os.Osmatch, NmapOsmatch{
+						Name:     name,
+						Accuracy: "100",
+					})
+				}
+			}
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return fmt.Errorf("reading line: %w", err)
+	}
+
+	if !seenHost {
+		return fmt.Errorf("no \"Host:\" lines found, not grepable output")
+	}
+
+	return flush()
+}
+
+func newGrepableHost(address string, hostname string) *NmapHost {
+	host := &NmapHost{}
+
+	addrtype := "ipv4"
+	if strings.Contains(address, ":") {
+		addrtype = "ipv6"
+	}
+	host.Address = []NmapAddress{{Addr: address, Addrtype: addrtype}}
+
+	if hostname != "" {
+		host.Hostnames.Hostname = []NmapHostname{{Name: hostname, Type: "PTR"}}
+	}
+
+	return host
+}
+
+// parseGrepableScanned converts the "# Ports scanned:" line into scaninfo.
+func parseGrepableScanned(line string) []NmapScaninfo {
+	scaninfo := make([]NmapScaninfo, 0)
+
+	for _, match := range grepableScannedRegexp.FindAllStringSubmatch(line, -1) {
+		if match[2] == "0" {
+			continue
+		}
+
+		protocol := strings.ToLower(match[1])
+		if protocol == "protocols" {
+			protocol = "ip"
+		}
+
+		scaninfo = append(scaninfo, NmapScaninfo{
+			Protocol:    protocol,
+			Numservices: match[2],
+			Services:    match[3],
+		})
+	}
+
+	return scaninfo
+}
+
+// splitGrepablePorts splits the "Ports:" field at the commas between ports.
+// Commas within the version column do not start a new port.
+func splitGrepablePorts(value string) []string {
+	entries := make([]string, 0)
+
+	for _, part := range strings.Split(value, ", ") {
+		if len(entries) > 0 && !startsWithPortNumber(part) {
+			entries[len(entries)-1] += ", " + part
+			continue
+		}
+		entries = append(entries, part)
+	}
+
+	return entries
+}
+
+func startsWithPortNumber(entry string) bool {
+	number, _, ok := strings.Cut(entry, "/")
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(number)
+	return err == nil
+}
+
+// parseGrepablePort parses one port, given as
+// "port/state/protocol/owner/service/rpc info/version/". Nmap replaces
+// slashes within the columns with "|", e.g. in "ssl|http".
+func parseGrepablePort(entry string) (NmapService, error) {
+	service := NmapService{}
+
+	columns := strings.Split(entry, "/")
+	if len(columns) < 7 {
+		return service, fmt.Errorf("expected 7 columns, got %d", len(columns))
+	}
+
+	port, err := strconv.Atoi(columns[0])
+	if err != nil {
+		return service, fmt.Errorf("parsing port number: %w", err)
+	}
+
+	service.Portid = port
+	service.State.State = columns[1]
+	service.Protocol = columns[2]
+
+	name := columns[4]
+	if tunnel, rest, ok := strings.Cut(name, "|"); ok {
+		service.Service.Tunnel = tunnel
+		name = rest
+	}
+	service.Service.Name = name
+	service.Service.Product = strings.ReplaceAll(columns[6], "|", "/")
+
+	return service, nil
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseGrepable(t *testing.T) {
+	hosts := parseTestdata(t, "testdata/scanme.gnmap")
+	if len(hosts) != 2 {
+		t.Fatalf("Expected 2 hosts, got %d", len(hosts))
+	}
+
+	down, up := hosts[0], hosts[1]
+	if down.Status.State != "down" || len(down.Hostnames.Hostname) != 0 {
+		t.Errorf("Wrong down host: %s %v", down.Status.State, down.Hostnames.Hostname)
+	}
+
+	if up.Status.State != "up" || up.String() != "45.33.32.156" || up.PreferredHostname() != "scanme.nmap.org" {
+		t.Errorf("Wrong host: %s %s (%s)", up.Status.State, up, up.PreferredHostname())
+	}
+
+	if len(up.Ports.Port) != 5 {
+		t.Fatalf("Expected 5 ports, got %d", len(up.Ports.Port))
+	}
+
+	http := up.Ports.Port[1]
+	if http.Portid != 80 || http.Service.Name != "http" || http.Info() != "Apache httpd 2.4.7 ((Ubuntu), PHP/5.5.9)" {
+		t.Errorf("Wrong port: %d %q %q", http.Portid, http.Service.Name, http.Info())
+	}
+
+	https := up.Ports.Port[2]
+	if https.NameWithTunnel() != "ssl/http" {
+		t.Errorf("Wrong service name: %q", https.NameWithTunnel())
+	}
+
+	if state := up.PortState("tcp", 31337); state != "filtered" {
+		t.Errorf("Wrong state of port 31337: %q", state)
+	}
+
+	if !up.ScannedPorts("tcp").Contains(1025) || up.ScannedPorts("tcp").Contains(1101) {
+		t.Errorf("Wrong scanned ports: %v", up.ScannedPorts("tcp"))
+	}
+
+	guess, ok := up.BestOS(90)
+	if !ok || guess.Name != "Linux 4.15 - 5.8" {
+		t.Errorf("Wrong OS: %v", guess)
+	}
+}
+
+func TestParseGrepableWithoutHosts(t *testing.T) {
+	input := "# Nmap 7.94 scan initiated Sat Oct 18 10:00:00 2026 as: nmap -oG - 10.0.0.1\n# Nmap done at Sat Oct 18 10:00:03 2026 -- 1 IP address (0 hosts up) scanned in 3.02 seconds\n"
+
+	err := ParseNmapGrepable(strings.NewReader(input), func(host NmapHost) error {
+		t.Errorf("Unexpected host %s", host)
+		return nil
+	})
+	if err == nil {
+		t.Error("Parsing output without hosts did not fail")
+	}
+}
+
+func TestReadGrepableArgs(t *testing.T) {
+	reader, err := os.Open("testdata/scanme.gnmap")
+	if err != nil {
+		t.Fatalf("Error opening testdata/scanme.gnmap: %v", err)
+	}
+	defer reader.Close()
+
+	args, err := ReadNmapArgs(reader)
+	if err != nil {
+		t.Fatalf("Error reading args: %v", err)
+	}
+
+	expected := "nmap -v -sV -O -oG scan.gnmap 45.33.32.156 192.168.1.0/30"
+	if args != expected {
+		t.Errorf("Got args %q, expected %q", args, expected)
+	}
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NmapOS is the operating system information of a host, mapped onto the
+// OS columns of Metasploit's hosts table. The vendor of the OS class is left
+// out, as the hosts table has no column for it.
+type NmapOS struct {
+	Name     string
+	Flavor   string
+	SP       string
+	Lang     string
+	Family   string
+	Arch     string
+	Purpose  string
+	Accuracy int
+}
+
+var servicePackRegexp = regexp.MustCompile(`(?i)\b(?:SP|Service Pack\s*)(\d+)\b`)
+
+var archRegexps = []struct {
+	arch   string
+	regexp *regexp.Regexp
+}{
+	{"x64", regexp.MustCompile(`(?i)\b(?:x86_64|x64|amd64|64-bit)\b`)},
+	{"x86", regexp.MustCompile(`(?i)\b(?:i[3-6]86|x86|32-bit)\b`)},
+	{"aarch64", regexp.MustCompile(`(?i)\b(?:aarch64|arm64)\b`)},
+	{"armle", regexp.MustCompile(`(?i)\barm`)},
+	{"mips", regexp.MustCompile(`(?i)\bmips`)},
+	{"ppc", regexp.MustCompile(`(?i)\b(?:ppc|powerpc)\b`)},
+	{"sparc", regexp.MustCompile(`(?i)\bsparc`)},
+}
+
+// BestOS returns the OS match with the highest accuracy that is at least
+// minAccuracy, together with its most accurate OS class.
+func (h NmapHost) BestOS(minAccuracy int) (NmapOS, bool) {
+	bestAccuracy := -1
+	var bestMatch NmapOsmatch
+
+	for _, match := range h.Os.Osmatch {
+		accuracy := parseAccuracy(match.Accuracy)
+		if accuracy > bestAccuracy {
+			bestAccuracy = accuracy
+			bestMatch = match
+		}
+	}
+
+	if bestAccuracy < 0 || bestAccuracy < minAccuracy {
+		return NmapOS{}, false
+	}
+
+	classes := bestMatch.Osclass
+	if len(classes) == 0 {
+		classes = h.Os.Osclass
+	}
+
+	bestClassAccuracy := -1
+	var bestClass NmapOsclass
+	for _, class := range classes {
+		accuracy := parseAccuracy(class.Accuracy)
+		if accuracy > bestClassAccuracy {
+			bestClassAccuracy = accuracy
+			bestClass = class
+		}
+	}
+
+	guess := NmapOS{
+		Name:     bestMatch.Name,
+		Flavor:   bestClass.Osgen,
+		Family:   bestClass.Osfamily,
+		Purpose:  bestClass.Type,
+		Accuracy: bestAccuracy,
+	}
+
+	for _, cpe := range bestClass.Cpe {
+		// cpe:/o:vendor:product:version:update:edition:language
+		parts := strings.Split(strings.TrimPrefix(cpe, "cpe:/"), ":")
+		if len(parts) > 4 && guess.SP == "" {
+			guess.SP = normalizeServicePack(parts[4])
+		}
+		if len(parts) > 6 && guess.Lang == "" {
+			guess.Lang = parts[6]
+		}
+	}
+
+	if guess.SP == "" {
+		guess.SP = normalizeServicePack(bestMatch.Name)
+	}
+
+	for _, candidate := range archRegexps {
+		if candidate.regexp.MatchString(bestMatch.Name) {
+			guess.Arch = candidate.arch
+			break
+		}
+	}
+
+	return guess, true
+}
+
+// normalizeServicePack converts strings like "sp1" or "Service Pack 2" to
+// Metasploit's format "SP1".
+func normalizeServicePack(s string) string {
+	match := servicePackRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return ""
+	}
+	return "SP" + match[1]
+}
+
+func parseAccuracy(accuracy string) int {
+	parsed, err := strconv.Atoi(accuracy)
+	if err != nil {
+		return 0
+	}
+	return parsed
+}
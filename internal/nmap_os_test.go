@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestBestOS(t *testing.T) {
+	data := `<host><os>
+<osmatch name="Microsoft Windows Server 2008 R2" accuracy="91" line="1">
+<osclass type="general purpose" vendor="Microsoft" osfamily="Windows" osgen="2008" accuracy="91"><cpe>cpe:/o:microsoft:windows_server_2008:r2</cpe></osclass>
+</osmatch>
+<osmatch name="Microsoft Windows 7 SP1 (64-bit)" accuracy="96" line="2">
+<osclass type="general purpose" vendor="Microsoft" osfamily="Windows" osgen="7" accuracy="96"><cpe>cpe:/o:microsoft:windows_7::sp1</cpe></osclass>
+</osmatch>
+</os></host>`
+
+	var host NmapHost
+	err := xml.Unmarshal([]byte(data), &host)
+	if err != nil {
+		t.Fatalf("Error parsing host: %v", err)
+	}
+
+	guess, ok := host.BestOS(0)
+	if !ok {
+		t.Fatal("No OS match found")
+	}
+
+	expected := NmapOS{
+		Name:     "Microsoft Windows 7 SP1 (64-bit)",
+		Flavor:   "7",
+		SP:       "SP1",
+		Family:   "Windows",
+		Arch:     "x64",
+		Purpose:  "general purpose",
+		Accuracy: 96,
+	}
+	if guess != expected {
+		t.Errorf("Got %+v, expected %+v", guess, expected)
+	}
+
+	if _, ok := host.BestOS(97); ok {
+		t.Error("OS match below minimum accuracy returned")
+	}
+
+	host.Os.Osmatch[1].Osclass[0].Cpe = []string{"cpe:/o:microsoft:windows_xp::sp3:professional:de"}
+	if guess, _ := host.BestOS(0); guess.SP != "SP3" || guess.Lang != "de" {
+		t.Errorf("Wrong service pack %q or language %q from CPE", guess.SP, guess.Lang)
+	}
+}
@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type portRange struct {
+	first int
+	last  int
+}
+
+// NmapPortSet is a set of ports as written by Nmap, e.g. "1,3-4,6-7".
+type NmapPortSet []portRange
+
+func ParsePortSet(spec string) (NmapPortSet, error) {
+	set := make(NmapPortSet, 0)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		firstStr, lastStr, isRange := strings.Cut(part, "-")
+		if !isRange {
+			lastStr = firstStr
+		}
+
+		first, err := strconv.Atoi(firstStr)
+		if err != nil {
+			return nil, fmt.Errorf("parsing port %q: %w", firstStr, err)
+		}
+
+		last, err := strconv.Atoi(lastStr)
+		if err != nil {
+			return nil, fmt.Errorf("parsing port %q: %w", lastStr, err)
+		}
+
+		set = append(set, portRange{first, last})
+	}
+
+	return set, nil
+}
+
+func (set NmapPortSet) Contains(port int) bool {
+	for _, r := range set {
+		if r.first <= port && port <= r.last {
+			return true
+		}
+	}
+	return false
+}
+
+// ScannedPorts returns the ports of the given protocol that were scanned on
+// the host. They are taken from <scaninfo> if available and otherwise from
+// the reported ports and <extraports> summaries.
+func (h NmapHost) ScannedPorts(protocol string) NmapPortSet {
+	set := make(NmapPortSet, 0)
+
+	for _, info := range h.Scaninfo {
+		if info.Protocol != protocol {
+			continue
+		}
+
+		services, err := ParsePortSet(info.Services)
+		if err != nil {
+			log.Warnf("Ignoring invalid services %q in <scaninfo>: %v", info.Services, err)
+			continue
+		}
+
+		set = append(set, services...)
+	}
+
+	if len(set) > 0 {
+		return set
+	}
+
+	for _, port := range h.Ports.Port {
+		if port.Protocol == protocol {
+			set = append(set, portRange{port.Portid, port.Portid})
+		}
+	}
+
+	for _, extraports := range h.Ports.Extraports {
+		for _, reasons := range extraports.Extrareasons {
+			if reasons.Proto != protocol {
+				continue
+			}
+
+			ports, err := ParsePortSet(reasons.Ports)
+			if err != nil {
+				log.Warnf("Ignoring invalid ports %q in <extrareasons>: %v", reasons.Ports, err)
+				continue
+			}
+
+			set = append(set, ports...)
+		}
+	}
+
+	return set
+}
+
+// PortState returns the state Nmap reported for the port, either explicitly
+// or as part of an <extraports> summary. Older Nmap versions and grepable
+// output do not list the ports of a summary, so a scanned port that is not
+// listed anywhere gets the state of such a summary, if there is only one. It
+// returns an empty string if the port is not mentioned.
+func (h NmapHost) PortState(protocol string, portid int) string {
+	for _, port := range h.Ports.Port {
+		if port.Protocol == protocol && port.Portid == portid {
+			return port.State.State
+		}
+	}
+
+	unlisted := make([]string, 0)
+
+	for _, extraports := range h.Ports.Extraports {
+		listed := false
+
+		for _, reasons := range extraports.Extrareasons {
+			if reasons.Ports == "" {
+				continue
+			}
+			listed = true
+
+			if reasons.Proto != protocol {
+				continue
+			}
+
+			ports, err := ParsePortSet(reasons.Ports)
+			if err == nil && ports.Contains(portid) {
+				return extraports.State
+			}
+		}
+
+		if !listed {
+			unlisted = append(unlisted, extraports.State)
+		}
+	}
+
+	if len(unlisted) == 1 && h.ScannedPorts(protocol).Contains(portid) {
+		return unlisted[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestPortState(t *testing.T) {
+	host := parseTestdata(t, "testdata/scanme.xml")[0]
+
+	cases := []struct {
+		protocol string
+		port     int
+		scanned  bool
+		state    string
+	}{
+		{"tcp", 22, true, "open"},
+		{"tcp", 1, true, "closed"},
+		{"tcp", 135, true, "filtered"},
+		{"tcp", 2, false, ""},
+		{"udp", 53, false, ""},
+	}
+
+	for _, c := range cases {
+		scanned := host.ScannedPorts(c.protocol).Contains(c.port)
+		if scanned != c.scanned {
+			t.Errorf("%s/%d: got scanned=%v, expected %v", c.protocol, c.port, scanned, c.scanned)
+		}
+
+		state := host.PortState(c.protocol, c.port)
+		if state != c.state {
+			t.Errorf("%s/%d: got state %q, expected %q", c.protocol, c.port, state, c.state)
+		}
+	}
+}
+
+func TestPortStateWithoutExtrareasons(t *testing.T) {
+	data := `<host>
+<ports>
+<extraports state="closed" count="99"><extrareasons reason="reset" count="99"/></extraports>
+<port protocol="tcp" portid="22"><state state="open" reason="syn-ack"/></port>
+</ports>
+</host>`
+
+	var host NmapHost
+	err := xml.Unmarshal([]byte(data), &host)
+	if err != nil {
+		t.Fatalf("Error parsing host: %v", err)
+	}
+	host.Scaninfo = []NmapScaninfo{{Protocol: "tcp", Numservices: "100", Services: "1-100"}}
+
+	cases := map[int]string{22: "open", 80: "closed", 200: ""}
+	for port, expected := range cases {
+		if state := host.PortState("tcp", port); state != expected {
+			t.Errorf("tcp/%d: got state %q, expected %q", port, state, expected)
+		}
+	}
+
+	if state := host.PortState("udp", 80); state != "" {
+		t.Errorf("udp/80: got state %q, expected none", state)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"net"
+	"strings"
 )
 
 // main struct Nmaprun generated with "XML to Go" (https://www.onlinetool.io/xmltogo/)
@@ -31,58 +32,118 @@ type NmapService struct {
 		ReasonTtl string `xml:"reason_ttl,attr"`
 	} `xml:"state"`
 	Service struct {
-		Text      string `xml:",chardata"`
-		Name      string `xml:"name,attr"`
-		Product   string `xml:"product,attr"`
-		Version   string `xml:"version,attr"`
-		Extrainfo string `xml:"extrainfo,attr"`
-		Method    string `xml:"method,attr"`
-		Conf      string `xml:"conf,attr"`
-		Tunnel    string `xml:"tunnel,attr"`
-		Hostname  string `xml:"hostname,attr"`
-		Servicefp string `xml:"servicefp,attr"`
-		Ostype    string `xml:"ostype,attr"`
+		Text      string   `xml:",chardata"`
+		Name      string   `xml:"name,attr"`
+		Product   string   `xml:"product,attr"`
+		Version   string   `xml:"version,attr"`
+		Extrainfo string   `xml:"extrainfo,attr"`
+		Method    string   `xml:"method,attr"`
+		Conf      string   `xml:"conf,attr"`
+		Tunnel    string   `xml:"tunnel,attr"`
+		Hostname  string   `xml:"hostname,attr"`
+		Servicefp string   `xml:"servicefp,attr"`
+		Ostype    string   `xml:"ostype,attr"`
+		Cpe       []string `xml:"cpe"`
+		// Banner is only written by masscan
+		Banner string `xml:"banner,attr"`
 	} `xml:"service"`
-	Script []struct {
+	Script NmapScripts `xml:"script"`
+}
+
+type NmapScript struct {
+	Text     string              `xml:",chardata"`
+	ID       string              `xml:"id,attr"`
+	Output   string              `xml:"output,attr"`
+	Elements []NmapScriptElement `xml:",any"`
+}
+
+// NmapScriptElement is a <table> or <elem> of structured script output.
+type NmapScriptElement struct {
+	XMLName  xml.Name
+	Text     string              `xml:",chardata"`
+	Key      string              `xml:"key,attr"`
+	Elements []NmapScriptElement `xml:",any"`
+}
+
+type NmapScaninfo struct {
+	Text        string `xml:",chardata"`
+	Type        string `xml:"type,attr"`
+	Protocol    string `xml:"protocol,attr"`
+	Numservices string `xml:"numservices,attr"`
+	Services    string `xml:"services,attr"`
+}
+
+type NmapOsclass struct {
+	Text     string   `xml:",chardata"`
+	Type     string   `xml:"type,attr"`
+	Vendor   string   `xml:"vendor,attr"`
+	Osfamily string   `xml:"osfamily,attr"`
+	Osgen    string   `xml:"osgen,attr"`
+	Accuracy string   `xml:"accuracy,attr"`
+	Cpe      []string `xml:"cpe"`
+}
+
+type NmapOsmatch struct {
+	Text     string        `xml:",chardata"`
+	Name     string        `xml:"name,attr"`
+	Accuracy string        `xml:"accuracy,attr"`
+	Line     string        `xml:"line,attr"`
+	Osclass  []NmapOsclass `xml:"osclass"`
+}
+
+type NmapAddress struct {
+	Text     string `xml:",chardata"`
+	Addr     string `xml:"addr,attr"`
+	Addrtype string `xml:"addrtype,attr"`
+	Vendor   string `xml:"vendor,attr"`
+}
+
+type NmapHostname struct {
+	Text string `xml:",chardata"`
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type NmapExtraports struct {
+	Text         string `xml:",chardata"`
+	State        string `xml:"state,attr"`
+	Count        string `xml:"count,attr"`
+	Extrareasons []struct {
 		Text   string `xml:",chardata"`
-		ID     string `xml:"id,attr"`
-		Output string `xml:"output,attr"`
-	} `xml:"script"`
+		Reason string `xml:"reason,attr"`
+		Count  string `xml:"count,attr"`
+		Proto  string `xml:"proto,attr"`
+		Ports  string `xml:"ports,attr"`
+	} `xml:"extrareasons"`
+}
+
+type NmapHop struct {
+	Text   string `xml:",chardata"`
+	Ttl    string `xml:"ttl,attr"`
+	Rtt    string `xml:"rtt,attr"`
+	Ipaddr string `xml:"ipaddr,attr"`
+	Host   string `xml:"host,attr"`
 }
 
 type NmapHost struct {
+	// Scaninfo is not part of <host>, but copied from the enclosing <nmaprun>
+	Scaninfo []NmapScaninfo `xml:"-"`
+
 	Text   string `xml:",chardata"`
 	Status struct {
 		Text   string `xml:",chardata"`
 		State  string `xml:"state,attr"`
 		Reason string `xml:"reason,attr"`
 	} `xml:"status"`
-	Address []struct {
-		Text     string `xml:",chardata"`
-		Addr     string `xml:"addr,attr"`
-		Addrtype string `xml:"addrtype,attr"`
-	} `xml:"address"`
+	Address   []NmapAddress `xml:"address"`
 	Hostnames struct {
-		Text     string `xml:",chardata"`
-		Hostname []struct {
-			Text string `xml:",chardata"`
-			Name string `xml:"name,attr"`
-			Type string `xml:"type,attr"`
-		} `xml:"hostname"`
+		Text     string         `xml:",chardata"`
+		Hostname []NmapHostname `xml:"hostname"`
 	} `xml:"hostnames"`
 	Ports struct {
-		Text       string `xml:",chardata"`
-		Extraports []struct {
-			Text         string `xml:",chardata"`
-			State        string `xml:"state,attr"`
-			Count        string `xml:"count,attr"`
-			Extrareasons []struct {
-				Text   string `xml:",chardata"`
-				Reason string `xml:"reason,attr"`
-				Count  string `xml:"count,attr"`
-			} `xml:"extrareasons"`
-		} `xml:"extraports"`
-		Port []NmapService `xml:"port"`
+		Text       string           `xml:",chardata"`
+		Extraports []NmapExtraports `xml:"extraports"`
+		Port       []NmapService    `xml:"port"`
 	} `xml:"ports"`
 	Os struct {
 		Text     string `xml:",chardata"`
@@ -92,20 +153,9 @@ type NmapHost struct {
 			Proto  string `xml:"proto,attr"`
 			Portid string `xml:"portid,attr"`
 		} `xml:"portused"`
-		Osclass []struct {
-			Text     string `xml:",chardata"`
-			Type     string `xml:"type,attr"`
-			Vendor   string `xml:"vendor,attr"`
-			Osfamily string `xml:"osfamily,attr"`
-			Osgen    string `xml:"osgen,attr"`
-			Accuracy string `xml:"accuracy,attr"`
-		} `xml:"osclass"`
-		Osmatch []struct {
-			Text     string `xml:",chardata"`
-			Name     string `xml:"name,attr"`
-			Accuracy string `xml:"accuracy,attr"`
-			Line     string `xml:"line,attr"`
-		} `xml:"osmatch"`
+		// Nmap versions before 4.22 write osclass directly below os
+		Osclass       []NmapOsclass `xml:"osclass"`
+		Osmatch       []NmapOsmatch `xml:"osmatch"`
 		Osfingerprint struct {
 			Text        string `xml:",chardata"`
 			Fingerprint string `xml:"fingerprint,attr"`
@@ -134,16 +184,10 @@ type NmapHost struct {
 		Values string `xml:"values,attr"`
 	} `xml:"tcptssequence"`
 	Trace struct {
-		Text  string `xml:",chardata"`
-		Port  string `xml:"port,attr"`
-		Proto string `xml:"proto,attr"`
-		Hop   []struct {
-			Text   string `xml:",chardata"`
-			Ttl    string `xml:"ttl,attr"`
-			Rtt    string `xml:"rtt,attr"`
-			Ipaddr string `xml:"ipaddr,attr"`
-			Host   string `xml:"host,attr"`
-		} `xml:"hop"`
+		Text  string    `xml:",chardata"`
+		Port  string    `xml:"port,attr"`
+		Proto string    `xml:"proto,attr"`
+		Hop   []NmapHop `xml:"hop"`
 	} `xml:"trace"`
 	Times struct {
 		Text   string `xml:",chardata"`
@@ -155,25 +199,23 @@ type NmapHost struct {
 		Text  string `xml:",chardata"`
 		Value string `xml:"value,attr"`
 	} `xml:"distance"`
+	Hostscript struct {
+		Text   string      `xml:",chardata"`
+		Script NmapScripts `xml:"script"`
+	} `xml:"hostscript"`
 }
 
 type Nmaprun struct {
-	XMLName          xml.Name `xml:"nmaprun"`
-	Text             string   `xml:",chardata"`
-	Scanner          string   `xml:"scanner,attr"`
-	Args             string   `xml:"args,attr"`
-	Start            string   `xml:"start,attr"`
-	Startstr         string   `xml:"startstr,attr"`
-	Version          string   `xml:"version,attr"`
-	Xmloutputversion string   `xml:"xmloutputversion,attr"`
-	Scaninfo         struct {
-		Text        string `xml:",chardata"`
-		Type        string `xml:"type,attr"`
-		Protocol    string `xml:"protocol,attr"`
-		Numservices string `xml:"numservices,attr"`
-		Services    string `xml:"services,attr"`
-	} `xml:"scaninfo"`
-	Verbose struct {
+	XMLName          xml.Name       `xml:"nmaprun"`
+	Text             string         `xml:",chardata"`
+	Scanner          string         `xml:"scanner,attr"`
+	Args             string         `xml:"args,attr"`
+	Start            string         `xml:"start,attr"`
+	Startstr         string         `xml:"startstr,attr"`
+	Version          string         `xml:"version,attr"`
+	Xmloutputversion string         `xml:"xmloutputversion,attr"`
+	Scaninfo         []NmapScaninfo `xml:"scaninfo"`
+	Verbose          struct {
 		Text  string `xml:",chardata"`
 		Level string `xml:"level,attr"`
 	} `xml:"verbose"`
@@ -243,6 +285,41 @@ func (h NmapHost) AllIPAddresses() []net.IP {
 	return addresses
 }
 
+// PreferredIPAddress returns the address used to identify the host in
+// Metasploit: the first address of the preferred family (see PreferIPv6) or
+// otherwise the first address.
+func (h NmapHost) PreferredIPAddress() net.IP {
+	allIPs := h.AllIPAddresses()
+
+	for _, ip := range allIPs {
+		isIPv6 := ip.To4() == nil
+		if isIPv6 == PreferIPv6 {
+			return ip
+		}
+	}
+
+	if len(allIPs) > 0 {
+		return allIPs[0]
+	}
+	return nil
+}
+
+// PreferredHostname returns the first hostname given by the user, or the
+// first hostname of any other type (e.g. PTR).
+func (h NmapHost) PreferredHostname() string {
+	for _, hostname := range h.Hostnames.Hostname {
+		if hostname.Type == "user" {
+			return hostname.Name
+		}
+	}
+
+	hostnames := h.AllHostnames()
+	if len(hostnames) > 0 {
+		return hostnames[0]
+	}
+	return ""
+}
+
 func (h NmapHost) AllHostnames() []string {
 	hostnames := make([]string, 0)
 
@@ -271,14 +348,14 @@ func (h NmapHost) AllMacAddresses() []net.HardwareAddr {
 }
 
 func (h NmapHost) String() string {
-	ips := h.AllIPAddresses()
-	if len(ips) > 0 {
-		return ips[0].String()
+	ip := h.PreferredIPAddress()
+	if ip != nil {
+		return ip.String()
 	}
 
-	hostnames := h.AllHostnames()
-	if len(hostnames) > 0 {
-		return hostnames[0]
+	hostname := h.PreferredHostname()
+	if hostname != "" {
+		return hostname
 	}
 
 	return "<unknown>"
@@ -292,6 +369,18 @@ func (s NmapService) NameWithTunnel() string {
 	return fmt.Sprintf("%s%s", prefix, s.Service.Name)
 }
 
+// Info returns the service description in the format of Metasploit's Nmap
+// importer: product, version and extra info separated by spaces.
+func (s NmapService) Info() string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{s.Service.Product, s.Service.Version, s.Service.Extrainfo} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
 func (s NmapService) String() string {
 	suffix := ""
 
@@ -301,3 +390,46 @@ func (s NmapService) String() string {
 
 	return fmt.Sprintf("%s:%d%s", s.Protocol, s.Portid, suffix)
 }
+
+// FullOutput returns the human-readable script output. Older Nmap versions
+// only write it as character data instead of the output attribute.
+func (s NmapScript) FullOutput() string {
+	if s.Output != "" {
+		return s.Output
+	}
+	return strings.TrimSpace(s.Text)
+}
+
+func (e NmapScriptElement) IsTable() bool {
+	return e.XMLName.Local == "table"
+}
+
+// Get returns the direct child with the given key.
+func (e NmapScriptElement) Get(key string) (NmapScriptElement, bool) {
+	for _, child := range e.Elements {
+		if child.Key == key {
+			return child, true
+		}
+	}
+	return NmapScriptElement{}, false
+}
+
+// GetText returns the text of the direct child <elem> with the given key.
+func (e NmapScriptElement) GetText(key string) string {
+	child, ok := e.Get(key)
+	if !ok || child.IsTable() {
+		return ""
+	}
+	return child.Text
+}
+
+// Texts returns the texts of all direct <elem> children.
+func (e NmapScriptElement) Texts() []string {
+	texts := make([]string, 0)
+	for _, child := range e.Elements {
+		if !child.IsTable() {
+			texts = append(texts, child.Text)
+		}
+	}
+	return texts
+}
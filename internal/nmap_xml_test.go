@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestPreferredAddress(t *testing.T) {
+	data := `<host>
+<address addr="192.0.2.10" addrtype="ipv4"/>
+<address addr="2001:db8::10" addrtype="ipv6"/>
+<hostnames>
+<hostname name="srv10.example.net" type="PTR"/>
+<hostname name="www.example.com" type="user"/>
+</hostnames>
+</host>`
+
+	var host NmapHost
+	err := xml.Unmarshal([]byte(data), &host)
+	if err != nil {
+		t.Fatalf("Error parsing host: %v", err)
+	}
+
+	if hostname := host.PreferredHostname(); hostname != "www.example.com" {
+		t.Errorf("Got preferred hostname %q, expected user-provided hostname", hostname)
+	}
+
+	defer func(old bool) { PreferIPv6 = old }(PreferIPv6)
+
+	for _, c := range []struct {
+		preferIPv6 bool
+		expected   string
+	}{{false, "192.0.2.10"}, {true, "2001:db8::10"}} {
+		PreferIPv6 = c.preferIPv6
+		if ip := host.PreferredIPAddress().String(); ip != c.expected {
+			t.Errorf("PreferIPv6=%v: got %q, expected %q", c.preferIPv6, ip, c.expected)
+		}
+	}
+}
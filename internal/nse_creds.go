@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NmapCredential is a valid account reported by an NSE script.
+type NmapCredential struct {
+	Username string
+	Password string
+}
+
+type credentialParser func(script NmapScript) []NmapCredential
+
+// credentialParsers maps script IDs to the parser for their output.
+var credentialParsers = map[string]credentialParser{
+	"ftp-anon":             parseFtpAnon,
+	"mysql-empty-password": parseMysqlEmptyPassword,
+	"ftp-brute":            parseCredsAccounts,
+	"http-brute":           parseCredsAccounts,
+	"http-form-brute":      parseCredsAccounts,
+	"ms-sql-brute":         parseCredsAccounts,
+	"mysql-brute":          parseCredsAccounts,
+	"pgsql-brute":          parseCredsAccounts,
+	"pop3-brute":           parseCredsAccounts,
+	"smb-brute":            parseCredsAccounts,
+	"snmp-brute":           parseCredsAccounts,
+	"ssh-brute":            parseCredsAccounts,
+	"telnet-brute":         parseCredsAccounts,
+	"vnc-brute":            parseCredsAccounts,
+}
+
+// Credentials returns the valid accounts found by the script, if there is a
+// parser for it.
+func (s NmapScript) Credentials() []NmapCredential {
+	parser, ok := credentialParsers[s.ID]
+	if !ok {
+		return nil
+	}
+	return parser(s)
+}
+
+// ftp-anon tries to log in as "anonymous" with the password "IEUser@".
+var ftpAnonRegexp = regexp.MustCompile(`Anonymous FTP login allowed`)
+
+func parseFtpAnon(script NmapScript) []NmapCredential {
+	if !ftpAnonRegexp.MatchString(script.FullOutput()) {
+		return nil
+	}
+	return []NmapCredential{{Username: "anonymous", Password: "IEUser@"}}
+}
+
+var mysqlEmptyPasswordRegexp = regexp.MustCompile(`(?m)^\s*(\S+) account has empty password`)
+
+func parseMysqlEmptyPassword(script NmapScript) []NmapCredential {
+	credentials := make([]NmapCredential, 0)
+
+	for _, match := range mysqlEmptyPasswordRegexp.FindAllStringSubmatch(script.FullOutput(), -1) {
+		username := match[1]
+		// the anonymous account is the one with an empty user name
+		if username == "anonymous" {
+			username = ""
+		}
+
+		credentials = append(credentials, NmapCredential{Username: username})
+	}
+
+	return credentials
+}
+
+// credsAccountRegexp matches lines of the creds library's text output, e.g.
+// "root:toor - Valid credentials" or "public - Valid credentials".
+var credsAccountRegexp = regexp.MustCompile(`(?m)^\s*(?:(\S*):)?(\S*) - Valid credentials`)
+
+// parseCredsAccounts reads the "Accounts" table written by scripts using the
+// brute and creds libraries, falling back to their text output.
+func parseCredsAccounts(script NmapScript) []NmapCredential {
+	credentials := make([]NmapCredential, 0)
+
+	accounts, ok := script.Lookup("Accounts")
+	if list, isList := accounts.([]interface{}); ok && isList {
+		for _, item := range list {
+			account, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			state, _ := account["state"].(string)
+			if !strings.HasPrefix(state, "Valid credentials") {
+				continue
+			}
+
+			username, _ := account["username"].(string)
+			password, _ := account["password"].(string)
+
+			credentials = append(credentials, NmapCredential{
+				Username: unquoteEmpty(username),
+				Password: unquoteEmpty(password),
+			})
+		}
+
+		return credentials
+	}
+
+	for _, match := range credsAccountRegexp.FindAllStringSubmatch(script.FullOutput(), -1) {
+		credentials = append(credentials, NmapCredential{
+			Username: unquoteEmpty(match[1]),
+			Password: unquoteEmpty(match[2]),
+		})
+	}
+
+	return credentials
+}
+
+// unquoteEmpty converts the "<empty>" placeholder of the creds library.
+func unquoteEmpty(s string) string {
+	if s == "<empty>" {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScriptCredentials(t *testing.T) {
+	host := parseTestdata(t, "testdata/creds.xml")[0]
+
+	expected := map[string][]NmapCredential{
+		"ftp-anon": {{"anonymous", "IEUser@"}},
+		"ssh-brute": {
+			{"msfadmin", "msfadmin"},
+			{"user", "user"},
+		},
+		"mysql-empty-password": {
+			{"root", ""},
+			{"", ""},
+		},
+		"snmp-brute": {
+			{"", "public"},
+			{"", "private"},
+		},
+	}
+
+	for _, port := range host.Ports.Port {
+		script := port.Script[0]
+
+		credentials := script.Credentials()
+		if !reflect.DeepEqual(credentials, expected[script.ID]) {
+			t.Errorf("%s: got %v, expected %v", script.ID, credentials, expected[script.ID])
+		}
+	}
+
+	unknown := NmapScript{ID: "http-title", Output: "admin:admin - Valid credentials"}
+	if credentials := unknown.Credentials(); len(credentials) != 0 {
+		t.Errorf("Credentials found in output of script without parser: %v", credentials)
+	}
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NmapScripts is a list of script results, as found in a <port> or
+// <hostscript> element.
+type NmapScripts []NmapScript
+
+// Get returns the result of the script with the given ID.
+func (scripts NmapScripts) Get(id string) (NmapScript, bool) {
+	for _, script := range scripts {
+		if script.ID == id {
+			return script, true
+		}
+	}
+	return NmapScript{}, false
+}
+
+// Lookup returns the value at a path that starts with the script ID, e.g.
+// "ssl-cert.subject.commonName". See NmapScript.Lookup.
+func (scripts NmapScripts) Lookup(path string) (interface{}, bool) {
+	id, rest, _ := strings.Cut(path, ".")
+
+	script, ok := scripts.Get(id)
+	if !ok {
+		return nil, false
+	}
+
+	return script.Lookup(rest)
+}
+
+// Value converts the structured output of the script into a tree of
+// map[string]interface{} (tables with keys), []interface{} (tables without
+// keys) and string (elems).
+func (s NmapScript) Value() interface{} {
+	return elementsValue(s.Elements)
+}
+
+// Lookup returns the value at a dot-separated path in the structured output,
+// e.g. "subject.commonName". Keys containing dots (such as CPEs) are matched
+// as a whole. Children without a key are addressed by their one-based
+// position, as in Lua, both in lists and in mixed tables. An empty path
+// returns the whole tree.
+func (s NmapScript) Lookup(path string) (interface{}, bool) {
+	if path == "" {
+		return s.Value(), true
+	}
+
+	return lookupValue(s.Value(), strings.Split(path, "."))
+}
+
+// LookupText is like Lookup, but only returns elem values.
+func (s NmapScript) LookupText(path string) (string, bool) {
+	value, ok := s.Lookup(path)
+	if !ok {
+		return "", false
+	}
+
+	text, ok := value.(string)
+	return text, ok
+}
+
+func (e NmapScriptElement) Value() interface{} {
+	if !e.IsTable() {
+		return e.Text
+	}
+	return elementsValue(e.Elements)
+}
+
+// elementsValue converts the children of a table. Tables in which no child
+// has a key become lists, all others become maps. Children without a key in
+// mixed tables are stored under their one-based position, as in Lua.
+func elementsValue(elements []NmapScriptElement) interface{} {
+	hasKeys := false
+	for _, element := range elements {
+		if element.Key != "" {
+			hasKeys = true
+			break
+		}
+	}
+
+	if !hasKeys {
+		list := make([]interface{}, 0, len(elements))
+		for _, element := range elements {
+			list = append(list, element.Value())
+		}
+		return list
+	}
+
+	table := make(map[string]interface{}, len(elements))
+	position := 0
+	for _, element := range elements {
+		key := element.Key
+		if key == "" {
+			position++
+			key = strconv.Itoa(position)
+		}
+		table[key] = element.Value()
+	}
+	return table
+}
+
+func lookupValue(value interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return value, true
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// prefer the longest key, so that keys containing dots can be matched
+		for n := len(path); n > 0; n-- {
+			child, ok := v[strings.Join(path[:n], ".")]
+			if !ok {
+				continue
+			}
+
+			result, ok := lookupValue(child, path[n:])
+			if ok {
+				return result, true
+			}
+		}
+	case []interface{}:
+		position, err := strconv.Atoi(path[0])
+		if err != nil || position < 1 || position > len(v) {
+			return nil, false
+		}
+		return lookupValue(v[position-1], path[1:])
+	}
+
+	return nil, false
+}
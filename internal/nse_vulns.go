@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NmapVuln is a vulnerability reported by an NSE script.
+type NmapVuln struct {
+	Name string
+	Info string
+	// Refs are reference names in Metasploit's format, e.g. "CVE-2017-0143".
+	Refs []string
+}
+
+// Metasploit reference prefixes for the ID types used by NSE's vulns library.
+var vulnsLibRefPrefixes = map[string]string{
+	"CVE":   "CVE",
+	"OSVDB": "OSVDB",
+	"BID":   "BID",
+	"EDB":   "EDB",
+	"MSB":   "MSB",
+}
+
+// Vulns extracts the vulnerabilities from the structured output of the
+// vulners script and of scripts using NSE's vulns library (the "vuln"
+// category).
+func (s NmapScript) Vulns() []NmapVuln {
+	if s.ID == "vulners" {
+		return vulnersVulns(s)
+	}
+
+	return vulnsLibVulns(s)
+}
+
+// vulnersVulns reads the output of the vulners script, which consists of one
+// table per CPE containing one table per finding.
+func vulnersVulns(script NmapScript) []NmapVuln {
+	vulns := make([]NmapVuln, 0)
+
+	for _, cpe := range script.Elements {
+		if !cpe.IsTable() {
+			continue
+		}
+
+		for _, finding := range cpe.Elements {
+			id := finding.GetText("id")
+			if finding.GetText("type") != "cve" || id == "" {
+				continue
+			}
+
+			info := fmt.Sprintf("%s (CVSS %s)", cpe.Key, finding.GetText("cvss"))
+			if finding.GetText("is_exploit") == "true" {
+				info += ", exploit available"
+			}
+
+			vulns = append(vulns, NmapVuln{
+				Name: id,
+				Info: info,
+				Refs: []string{id},
+			})
+		}
+	}
+
+	return vulns
+}
+
+// vulnsLibVulns reads the output of scripts using the vulns library, which
+// writes one table per vulnerability with "title", "state", "ids" etc.
+func vulnsLibVulns(script NmapScript) []NmapVuln {
+	vulns := make([]NmapVuln, 0)
+
+	for _, table := range script.Elements {
+		title := table.GetText("title")
+		state := table.GetText("state")
+
+		if !table.IsTable() || title == "" {
+			continue
+		}
+
+		if !strings.Contains(state, "VULNERABLE") || strings.Contains(state, "NOT VULNERABLE") {
+			continue
+		}
+
+		vuln := NmapVuln{
+			Name: title,
+			Info: fmt.Sprintf("%s: %s", script.ID, state),
+			Refs: make([]string, 0),
+		}
+
+		if description, ok := table.Get("description"); ok {
+			lines := description.Texts()
+			if len(lines) > 0 {
+				vuln.Info = strings.TrimSpace(strings.Join(lines, "\n"))
+			}
+		}
+
+		if ids, ok := table.Get("ids"); ok {
+			for _, id := range ids.Texts() {
+				kind, value, found := strings.Cut(id, ":")
+				prefix, known := vulnsLibRefPrefixes[kind]
+				if !found || !known {
+					continue
+				}
+
+				vuln.Refs = appendUnique(vuln.Refs, fmt.Sprintf("%s-%s", prefix, strings.TrimPrefix(value, prefix+"-")))
+			}
+		}
+
+		if refs, ok := table.Get("refs"); ok {
+			for _, url := range refs.Texts() {
+				vuln.Refs = appendUnique(vuln.Refs, fmt.Sprintf("URL-%s", url))
+			}
+		}
+
+		vulns = append(vulns, vuln)
+	}
+
+	return vulns
+}
+
+func appendUnique(list []string, item string) []string {
+	for _, existing := range list {
+		if existing == item {
+			return list
+		}
+	}
+	return append(list, item)
+}
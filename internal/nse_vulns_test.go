@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScriptVulns(t *testing.T) {
+	host := parseTestdata(t, "testdata/vulns.xml")[0]
+
+	vulners := host.Ports.Port[0].Script[0].Vulns()
+	if len(vulners) != 2 {
+		t.Fatalf("Expected 2 CVEs from vulners, got %d: %v", len(vulners), vulners)
+	}
+
+	if vulners[0].Name != "CVE-2015-5600" || !reflect.DeepEqual(vulners[0].Refs, []string{"CVE-2015-5600"}) {
+		t.Errorf("Unexpected vulners vuln: %+v", vulners[0])
+	}
+
+	ms17010 := host.Hostscript.Script[0].Vulns()
+	if len(ms17010) != 1 {
+		t.Fatalf("Expected 1 vuln from smb-vuln-ms17-010, got %d", len(ms17010))
+	}
+
+	if ms17010[0].Name != "Remote Code Execution vulnerability in Microsoft SMBv1 servers (ms17-010)" {
+		t.Errorf("Unexpected vuln name %q", ms17010[0].Name)
+	}
+
+	if len(ms17010[0].Refs) != 4 || ms17010[0].Refs[0] != "CVE-2017-0143" {
+		t.Errorf("Unexpected refs: %v", ms17010[0].Refs)
+	}
+}
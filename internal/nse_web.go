@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// NmapWebPage is a web page reconstructed from the output of HTTP scripts.
+type NmapWebPage struct {
+	Path     string
+	Code     int
+	Ctype    string
+	Cookie   string
+	Location string
+	// Headers maps lower-case header names to their values.
+	Headers map[string][]string
+	Body    string
+}
+
+// IsHTTP returns true for services that Nmap identified as HTTP, including
+// HTTP tunneled through SSL/TLS.
+func (s NmapService) IsHTTP() bool {
+	return s.Service.Name == "http" || s.Service.Name == "https"
+}
+
+// IsHTTPS returns true for HTTP services that use SSL/TLS.
+func (s NmapService) IsHTTPS() bool {
+	return s.Service.Name == "https" || (s.IsHTTP() && s.Service.Tunnel == "ssl")
+}
+
+// WebPages returns the pages found by the http-title, http-headers and
+// http-robots.txt scripts.
+func (s NmapService) WebPages() []NmapWebPage {
+	pages := make([]NmapWebPage, 0)
+
+	root := NmapWebPage{Path: "/", Code: 200}
+	hasRoot := false
+
+	if script, ok := s.Script.Get("http-title"); ok {
+		title, hasTitle := script.LookupText("title")
+		if hasTitle {
+			root.Body = fmt.Sprintf("<title>%s</title>", html.EscapeString(title))
+		}
+
+		if redirect, ok := script.LookupText("redirect_url"); ok {
+			root.Code = 302
+			root.Location = redirect
+		}
+
+		hasRoot = hasTitle || root.Location != ""
+	}
+
+	if script, ok := s.Script.Get("http-headers"); ok {
+		root.Headers = parseScriptHeaders(script.FullOutput())
+		if len(root.Headers) > 0 {
+			hasRoot = true
+		}
+
+		root.Ctype = firstHeader(root.Headers, "content-type")
+		root.Cookie = firstHeader(root.Headers, "set-cookie")
+		if location := firstHeader(root.Headers, "location"); location != "" {
+			root.Location = location
+		}
+	}
+
+	if hasRoot {
+		pages = append(pages, root)
+	}
+
+	if script, ok := s.Script.Get("http-robots.txt"); ok {
+		robots := NmapWebPage{
+			Path:  "/robots.txt",
+			Code:  200,
+			Ctype: "text/plain",
+		}
+
+		// the first line is a summary like "2 disallowed entries"
+		lines := strings.Split(strings.TrimSpace(script.FullOutput()), "\n")
+		if len(lines) > 1 {
+			for _, entry := range strings.Fields(strings.Join(lines[1:], " ")) {
+				robots.Body += fmt.Sprintf("Disallow: %s\n", entry)
+			}
+		}
+
+		pages = append(pages, robots)
+	}
+
+	return pages
+}
+
+// SplitPath splits the page path into path and query as stored by
+// Metasploit.
+func (p NmapWebPage) SplitPath() (string, string) {
+	parsed, err := url.Parse(p.Path)
+	if err != nil {
+		return p.Path, ""
+	}
+	return parsed.Path, parsed.RawQuery
+}
+
+// parseScriptHeaders parses the output of http-headers, which contains one
+// header per line and ends with the request type.
+func parseScriptHeaders(output string) map[string][]string {
+	headers := make(map[string][]string)
+
+	for _, line := range strings.Split(output, "\n") {
+		name, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found || strings.HasPrefix(name, "(") || strings.Contains(name, " ") {
+			continue
+		}
+
+		name = strings.ToLower(name)
+		headers[name] = append(headers[name], strings.TrimSpace(value))
+	}
+
+	return headers
+}
+
+func firstHeader(headers map[string][]string, name string) string {
+	values := headers[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWebPages(t *testing.T) {
+	host := parseTestdata(t, "testdata/http.xml")[0]
+
+	http, https := host.Ports.Port[0], host.Ports.Port[1]
+
+	if !http.IsHTTP() || http.IsHTTPS() {
+		t.Errorf("Port 80 not detected as plain HTTP")
+	}
+
+	if !https.IsHTTP() || !https.IsHTTPS() {
+		t.Errorf("Port 443 not detected as HTTPS")
+	}
+
+	pages := http.WebPages()
+	if len(pages) != 2 {
+		t.Fatalf("Expected 2 pages on port 80, got %d", len(pages))
+	}
+
+	root := pages[0]
+	if root.Path != "/" || root.Code != 200 || root.Body != "<title>Go ahead and ScanMe!</title>" || root.Ctype != "text/html" {
+		t.Errorf("Unexpected root page: %+v", root)
+	}
+
+	if !reflect.DeepEqual(root.Headers["server"], []string{"Apache/2.4.7 (Ubuntu)"}) {
+		t.Errorf("Unexpected Server header: %v", root.Headers["server"])
+	}
+
+	robots := pages[1]
+	if robots.Path != "/robots.txt" || robots.Body != "Disallow: /private/\nDisallow: /cgi-bin/\n" {
+		t.Errorf("Unexpected robots.txt page: %+v", robots)
+	}
+
+	pages = https.WebPages()
+	if len(pages) != 1 || pages[0].Code != 302 || pages[0].Location != "https://scanme.nmap.org/login?next=%2F" {
+		t.Errorf("Unexpected pages on port 443: %+v", pages)
+	}
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"os"
+	"strconv"
+)
+
+const MinOSAccuracyEnvVar = "DB_NMAP_MIN_OS_ACCURACY"
+const IncludeClosedPortsEnvVar = "DB_NMAP_INCLUDE_CLOSED"
+const ReconcileServicesEnvVar = "DB_NMAP_RECONCILE"
+const PreferIPv6EnvVar = "DB_NMAP_PREFER_IPV6"
+const RegisterRoutersEnvVar = "DB_NMAP_REGISTER_ROUTERS"
+const CreateMissingWorkspaceEnvVar = "DB_NMAP_CREATE_WORKSPACE"
+const StrictEnvVar = "DB_NMAP_STRICT"
+
+// MinOSAccuracy is the minimum accuracy (in percent) an OS match needs to be
+// stored on the host.
+var MinOSAccuracy = envInt(MinOSAccuracyEnvVar, 0)
+
+// IncludeClosedPorts enables the import of closed and filtered services, and
+// of hosts that are up but do not have any open ports.
+var IncludeClosedPorts = envBool(IncludeClosedPortsEnvVar, false)
+
+// ReconcileServices enables marking known open services as closed if their
+// port was scanned again, but not found open.
+var ReconcileServices = envBool(ReconcileServicesEnvVar, false)
+
+// PreferIPv6 selects the IPv6 address of dual-stack hosts as the address
+// stored on the Metasploit host.
+var PreferIPv6 = envBool(PreferIPv6EnvVar, false)
+
+// RegisterRouters enables creating hosts with purpose "router" for the
+// intermediate hops of traceroutes.
+var RegisterRouters = envBool(RegisterRoutersEnvVar, false)
+
+// CreateMissingWorkspace enables creating the destination workspace if it
+// does not exist yet.
+var CreateMissingWorkspace = envBool(CreateMissingWorkspaceEnvVar, false)
+
+// Strict makes the commands exit with a non-zero code if any host could not
+// be imported.
+var Strict = envBool(StrictEnvVar, false)
+
+// DryRun disables all changes to the database.
+var DryRun = false
+
+func envString(name string, fallback string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func envInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Warnf("Ignoring invalid value %q of environment variable %s: %v", value, name, err)
+		return fallback
+	}
+
+	return parsed
+}
+
+func envBool(name string, fallback bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Warnf("Ignoring invalid value %q of environment variable %s: %v", value, name, err)
+		return fallback
+	}
+
+	return parsed
+}
@@ -0,0 +1,257 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Parser reads the output format of a scanner.
+type Parser interface {
+	// Name describes the format, e.g. "Nmap XML".
+	Name() string
+	// Sniff returns true if prefix, the beginning of an input without
+	// leading whitespace, is in this format.
+	Sniff(prefix []byte) bool
+	// Parse calls handle for each host in reader.
+	Parse(reader io.Reader, handle HandleHostFunc) error
+}
+
+// funcParser implements Parser with functions.
+type funcParser struct {
+	name  string
+	sniff func(prefix []byte) bool
+	parse func(reader io.Reader, handle HandleHostFunc) error
+}
+
+func (p funcParser) Name() string {
+	return p.name
+}
+
+func (p funcParser) Sniff(prefix []byte) bool {
+	return p.sniff(prefix)
+}
+
+func (p funcParser) Parse(reader io.Reader, handle HandleHostFunc) error {
+	return p.parse(reader, handle)
+}
+
+const (
+	formatNmapXML      = "Nmap XML"
+	formatNmapGrepable = "Nmap grepable"
+	formatMasscanXML   = "masscan XML"
+	formatMasscanJSON  = "masscan JSON"
+	formatHttpxJSON    = "httpx JSON"
+	formatNaabuJSON    = "naabu JSON"
+	formatRustscan     = "RustScan greppable"
+)
+
+// Parsers are the known formats, in the order they are tried. Formats that
+// are harder to recognize come later.
+var Parsers = []Parser{
+	funcParser{formatNmapGrepable, sniffNmapGrepable, ParseNmapGrepable},
+	funcParser{formatMasscanXML, sniffMasscanXML, ParseMasscanXML},
+	funcParser{formatNmapXML, sniffNmapXML, ParseNmapXML},
+	funcParser{formatMasscanJSON, sniffMasscanJSON, ParseMasscanJSON},
+	funcParser{formatHttpxJSON, sniffHttpxJSON, ParseHttpxJSON},
+	funcParser{formatNaabuJSON, sniffNaabuJSON, ParseNaabuJSON},
+	funcParser{formatRustscan, sniffRustscan, ParseRustscan},
+}
+
+// sniffLength is the number of bytes the parsers can look at.
+const sniffLength = 16 * 1024
+
+// DetectParser peeks at the beginning of reader and returns the first parser
+// that recognizes the format. It fails if the input is empty or in an unknown
+// format.
+func DetectParser(reader *bufio.Reader) (Parser, error) {
+	prefix, _ := reader.Peek(sniffLength)
+	prefix = bytes.TrimLeft(prefix, "\ufeff \t\r\n")
+
+	if len(prefix) == 0 {
+		return nil, fmt.Errorf("no scan results found, the input is empty")
+	}
+
+	for _, parser := range Parsers {
+		if parser.Sniff(prefix) {
+			return parser, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unrecognized format of scan results")
+}
+
+// ParseScanResults detects the format (and compression) of the scan results
+// in reader and calls handle for each host.
+func ParseScanResults(reader io.Reader, handle HandleHostFunc) error {
+	decompressed, err := Decompress(reader)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+
+	buffered := bufio.NewReaderSize(decompressed, sniffLength)
+
+	parser, err := DetectParser(buffered)
+	if err != nil {
+		return err
+	}
+	log.Debugf("Reading %s output.", parser.Name())
+
+	return parser.Parse(buffered, handle)
+}
+
+func sniffNmapXML(prefix []byte) bool {
+	return bytes.HasPrefix(prefix, []byte("<")) && bytes.Contains(prefix, []byte("<nmaprun"))
+}
+
+// firstJSONLine returns the first line of JSON lines, or of a JSON list with
+// one object per line.
+func firstJSONLine(prefix []byte) []byte {
+	prefix = bytes.TrimLeft(prefix, "[ \t\r\n")
+	line, _, _ := bytes.Cut(prefix, []byte("\n"))
+
+	if !bytes.HasPrefix(line, []byte("{")) {
+		return nil
+	}
+	return line
+}
+
+// hasJSONKeys returns true if line contains all keys.
+func hasJSONKeys(line []byte, keys ...string) bool {
+	for _, key := range keys {
+		if !bytes.Contains(line, []byte(`"`+key+`"`)) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseJSONLines calls parse for each non-empty line of JSON lines. The
+// syntax of a JSON list with one object per line is skipped, so that such
+// lists can be read as a stream, too.
+func parseJSONLines(reader io.Reader, parse func(line []byte) error) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		line = bytes.TrimSuffix(bytes.TrimPrefix(line, []byte("[")), []byte("]"))
+		line = bytes.TrimSuffix(bytes.TrimPrefix(line, []byte(",")), []byte(","))
+		line = bytes.TrimSpace(line)
+
+		if len(line) == 0 {
+			continue
+		}
+
+		err := parse(line)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return fmt.Errorf("reading line: %w", err)
+	}
+
+	return nil
+}
+
+// mergedHosts collects the hosts of formats that report every port (or
+// banner) separately and in no particular order, merging them into one host
+// per address.
+type mergedHosts struct {
+	addresses []string
+	hosts     map[string]*NmapHost
+	ports     map[mergedPort]int
+}
+
+// mergedPort identifies a port of a merged host, to find its index in the
+// host's ports.
+type mergedPort struct {
+	address  string
+	protocol string
+	portid   int
+}
+
+func newMergedHosts() *mergedHosts {
+	return &mergedHosts{
+		addresses: make([]string, 0),
+		hosts:     make(map[string]*NmapHost),
+		ports:     make(map[mergedPort]int),
+	}
+}
+
+// host returns the host with the given address, which is up.
+func (m *mergedHosts) host(address string) *NmapHost {
+	host, ok := m.hosts[address]
+	if ok {
+		return host
+	}
+
+	host = &NmapHost{}
+	host.Status.State = "up"
+
+	addrtype := "ipv4"
+	if strings.Contains(address, ":") {
+		addrtype = "ipv6"
+	}
+	host.Address = []NmapAddress{{Addr: address, Addrtype: addrtype}}
+
+	m.hosts[address] = host
+	m.addresses = append(m.addresses, address)
+
+	return host
+}
+
+// port returns the port of host, which is open unless set otherwise. The
+// pointer is only valid until the next port is added.
+func (m *mergedHosts) port(host *NmapHost, protocol string, portid int) *NmapService {
+	key := mergedPort{host.Address[0].Addr, protocol, portid}
+
+	if i, ok := m.ports[key]; ok {
+		return &host.Ports.Port[i]
+	}
+
+	service := NmapService{Protocol: protocol, Portid: portid}
+	service.State.State = "open"
+
+	m.ports[key] = len(host.Ports.Port)
+	host.Ports.Port = append(host.Ports.Port, service)
+	return &host.Ports.Port[len(host.Ports.Port)-1]
+}
+
+// addHostname adds a hostname given as a scan target.
+func (m *mergedHosts) addHostname(host *NmapHost, hostname string) {
+	for _, existing := range host.Hostnames.Hostname {
+		if existing.Name == hostname {
+			return
+		}
+	}
+
+	host.Hostnames.Hostname = append(host.Hostnames.Hostname, NmapHostname{Name: hostname, Type: "user"})
+}
+
+// handleAll calls handle for each host, in the order they were first seen.
+func (m *mergedHosts) handleAll(handle HandleHostFunc) error {
+	for _, address := range m.addresses {
+		host := m.hosts[address]
+
+		for i := range host.Ports.Port {
+			service := &host.Ports.Port[i].Service
+			if service.Tunnel == "ssl" && service.Name == "" {
+				// TLS, but nothing known about the protocol inside
+				service.Name, service.Tunnel = "ssl", ""
+			}
+		}
+
+		err := handle(*host)
+		if err != nil {
+			return fmt.Errorf("handling host %s: %w", address, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDetectParser(t *testing.T) {
+	formats := map[string]string{
+		"testdata/scanme.xml":     formatNmapXML,
+		"testdata/scanme.gnmap":   formatNmapGrepable,
+		"testdata/masscan.xml":    formatMasscanXML,
+		"testdata/masscan.json":   formatMasscanJSON,
+		"testdata/masscan.ndjson": formatMasscanJSON,
+		"testdata/naabu.json":     formatNaabuJSON,
+		"testdata/httpx.json":     formatHttpxJSON,
+		"testdata/rustscan.txt":   formatRustscan,
+	}
+
+	ndjson := []byte(`{"ip":"10.0.0.5","timestamp":"1760781122","port":53,"proto":"udp","rec_type":"status","data":{"status":"open"}}`)
+	if sniffNaabuJSON(ndjson) {
+		t.Error("masscan NDJSON detected as naabu JSON")
+	}
+
+	for filename, expected := range formats {
+		reader, err := os.Open(filename)
+		if err != nil {
+			t.Fatalf("Error opening %q: %v", filename, err)
+		}
+
+		parser, err := DetectParser(bufio.NewReaderSize(reader, sniffLength))
+		reader.Close()
+
+		if err != nil {
+			t.Errorf("Error detecting %q: %v", filename, err)
+		} else if parser.Name() != expected {
+			t.Errorf("Detected %q as %s, expected %s", filename, parser.Name(), expected)
+		}
+	}
+}
+
+func TestParseUnrecognized(t *testing.T) {
+	inputs := []string{
+		"",
+		"\n  \n",
+		"not a scan\n",
+		"#masscan\nopen tcp 80 10.0.0.5 1697472000\n# end\n",
+		`{"host": "10.0.0.5", "status": "up"}` + "\n",
+		"# Nmap 7.94 scan initiated Sat Oct 18 10:00:00 2026 as: nmap -oN scan.nmap scanme.nmap.org\nNmap scan report for scanme.nmap.org (45.33.32.156)\nHost is up (0.15s latency).\nNot shown: 998 closed tcp ports (reset)\nPORT   STATE SERVICE\n22/tcp open  ssh\n80/tcp open  http\n\n# Nmap done at Sat Oct 18 10:00:12 2026 -- 1 IP address (1 host up) scanned in 12.01 seconds\n",
+	}
+
+	for _, input := range inputs {
+		err := ParseScanResults(strings.NewReader(input), func(host NmapHost) error {
+			t.Errorf("Unexpected host %s in %q", host, input)
+			return nil
+		})
+		if err == nil {
+			t.Errorf("Parsing %q did not fail", input)
+		}
+	}
+}
+
+func TestParseNaabu(t *testing.T) {
+	hosts := parseTestdata(t, "testdata/naabu.json")
+	if len(hosts) != 2 {
+		t.Fatalf("Expected 2 hosts, got %d", len(hosts))
+	}
+
+	scanme := hosts[0]
+	if scanme.String() != "45.33.32.156" || scanme.PreferredHostname() != "scanme.nmap.org" || len(scanme.Ports.Port) != 2 {
+		t.Errorf("Wrong host %s (%s) with %d ports", scanme, scanme.PreferredHostname(), len(scanme.Ports.Port))
+	}
+
+	https := hosts[1].Ports.Port[0]
+	if https.Portid != 443 || https.State.State != "open" || https.NameWithTunnel() != "ssl" {
+		t.Errorf("Wrong port %d (%s) %q", https.Portid, https.State.State, https.NameWithTunnel())
+	}
+}
+
+func TestParseHttpx(t *testing.T) {
+	hosts := parseTestdata(t, "testdata/httpx.json")
+	if len(hosts) != 1 {
+		t.Fatalf("Expected 1 host, got %d", len(hosts))
+	}
+
+	host := hosts[0]
+	if host.String() != "93.184.216.34" || host.PreferredHostname() != "www.example.com" || len(host.Ports.Port) != 2 {
+		t.Fatalf("Wrong host %s (%s) with %d ports", host, host.PreferredHostname(), len(host.Ports.Port))
+	}
+
+	https, http := host.Ports.Port[0], host.Ports.Port[1]
+	if !https.IsHTTPS() || https.Portid != 443 || https.Info() != "ECS (dcb/7F83) Azure, Bootstrap" {
+		t.Errorf("Wrong service %d %q %q", https.Portid, https.NameWithTunnel(), https.Info())
+	}
+
+	pages := https.WebPages()
+	if len(pages) != 1 || pages[0].Body != "<title>Example Domain</title>" {
+		t.Errorf("Wrong web pages: %v", pages)
+	}
+
+	pages = http.WebPages()
+	if len(pages) != 1 || pages[0].Code != 302 || pages[0].Location != "https://www.example.com/" {
+		t.Errorf("Wrong web pages: %v", pages)
+	}
+}
+
+func TestParseRustscan(t *testing.T) {
+	hosts := parseTestdata(t, "testdata/rustscan.txt")
+	if len(hosts) != 2 {
+		t.Fatalf("Expected 2 hosts, got %d", len(hosts))
+	}
+
+	if len(hosts[0].Ports.Port) != 4 || hosts[0].PortState("tcp", 31337) != "open" {
+		t.Errorf("Wrong ports: %v", hosts[0].Ports.Port)
+	}
+
+	// a host with all ports open exceeds the default line length of bufio.Scanner
+	ports := make([]string, 0, 65535)
+	for port := 1; port <= 65535; port++ {
+		ports = append(ports, strconv.Itoa(port))
+	}
+	line := "10.0.0.9 -> [" + strings.Join(ports, ",") + "]\n"
+
+	var host NmapHost
+	err := ParseRustscan(strings.NewReader(line), func(h NmapHost) error {
+		host = h
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error parsing long line: %v", err)
+	}
+	if len(host.Ports.Port) != 65535 {
+		t.Errorf("Expected 65535 ports, got %d", len(host.Ports.Port))
+	}
+}
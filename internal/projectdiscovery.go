@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// naabuRecord is a line of naabu's JSON output (-json). Depending on the
+// version, the port is a number or an object.
+type naabuRecord struct {
+	Host     string          `json:"host"`
+	IP       string          `json:"ip"`
+	Port     json.RawMessage `json:"port"`
+	Protocol string          `json:"protocol"`
+	TLS      bool            `json:"tls"`
+}
+
+// sniffNaabuJSON excludes masscan's NDJSON records, which have the same keys.
+func sniffNaabuJSON(prefix []byte) bool {
+	line := firstJSONLine(prefix)
+	return hasJSONKeys(line, "ip", "port") && !hasJSONKeys(line, "rec_type")
+}
+
+// ParseNaabuJSON reads naabu's JSON lines output (-json).
+func ParseNaabuJSON(reader io.Reader, handle HandleHostFunc) error {
+	hosts := newMergedHosts()
+
+	err := parseJSONLines(reader, func(line []byte) error {
+		record := naabuRecord{}
+		err := json.Unmarshal(line, &record)
+		if err != nil {
+			return fmt.Errorf("parsing record %q: %w", line, err)
+		}
+
+		port := struct {
+			Port int
+			TLS  bool
+		}{}
+		err = json.Unmarshal(record.Port, &port.Port)
+		if err != nil {
+			err = json.Unmarshal(record.Port, &port)
+		}
+		if err != nil || port.Port == 0 || net.ParseIP(record.IP) == nil {
+			log.Warnf("Ignoring invalid naabu record %q.", line)
+			return nil
+		}
+
+		protocol := strings.ToLower(record.Protocol)
+		if protocol == "" {
+			protocol = "tcp"
+		}
+
+		host := hosts.host(record.IP)
+		if record.Host != "" && record.Host != record.IP {
+			hosts.addHostname(host, record.Host)
+		}
+
+		service := hosts.port(host, protocol, port.Port)
+		if record.TLS || port.TLS {
+			service.Service.Tunnel = "ssl"
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return hosts.handleAll(handle)
+}
+
+// httpxRecord is a line of httpx's JSON output (-json). Older versions write
+// the address to "ip" instead of "host" and "a".
+type httpxRecord struct {
+	URL        string          `json:"url"`
+	Input      string          `json:"input"`
+	Host       string          `json:"host"`
+	IP         string          `json:"ip"`
+	A          []string        `json:"a"`
+	Port       json.RawMessage `json:"port"`
+	Scheme     string          `json:"scheme"`
+	Title      string          `json:"title"`
+	Webserver  string          `json:"webserver"`
+	Tech       []string        `json:"tech"`
+	StatusCode int             `json:"status_code"`
+	Location   string          `json:"location"`
+}
+
+// address returns the IP address of the web server.
+func (r httpxRecord) address() string {
+	for _, candidate := range append([]string{r.IP, r.Host}, r.A...) {
+		if net.ParseIP(candidate) != nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// port returns the port of the web server, which is a string or a number
+// depending on the version, or else the port of the URL.
+func (r httpxRecord) port(u *url.URL) int {
+	var port int
+	if json.Unmarshal(r.Port, &port) == nil && port > 0 {
+		return port
+	}
+
+	var text string
+	if json.Unmarshal(r.Port, &text) == nil {
+		if port, err := strconv.Atoi(text); err == nil {
+			return port
+		}
+	}
+
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		return port
+	}
+	if u.Scheme == "https" {
+		return 443
+	}
+	return 80
+}
+
+func sniffHttpxJSON(prefix []byte) bool {
+	return hasJSONKeys(firstJSONLine(prefix), "url", "scheme")
+}
+
+// ParseHttpxJSON reads httpx's JSON lines output (-json). Each web server
+// becomes an HTTP service. Its title (and redirect) are stored like the
+// results of the http-title script, the web server and technologies as
+// product and extra info.
+func ParseHttpxJSON(reader io.Reader, handle HandleHostFunc) error {
+	hosts := newMergedHosts()
+
+	err := parseJSONLines(reader, func(line []byte) error {
+		record := httpxRecord{}
+		err := json.Unmarshal(line, &record)
+		if err != nil {
+			return fmt.Errorf("parsing record %q: %w", line, err)
+		}
+
+		u, err := url.Parse(record.URL)
+		address := record.address()
+		if err != nil || address == "" {
+			log.Warnf("Ignoring httpx record of %q without address.", record.URL)
+			return nil
+		}
+
+		host := hosts.host(address)
+		if hostname := u.Hostname(); hostname != "" && net.ParseIP(hostname) == nil {
+			hosts.addHostname(host, hostname)
+		}
+
+		service := hosts.port(host, "tcp", record.port(u))
+		service.Service.Name = "http"
+		if record.Scheme == "https" || u.Scheme == "https" {
+			service.Service.Tunnel = "ssl"
+		}
+		setIfNotEmpty(&service.Service.Product, record.Webserver)
+		setIfNotEmpty(&service.Service.Extrainfo, strings.Join(record.Tech, ", "))
+
+		titleScript := NmapScript{ID: "http-title", Output: record.Title}
+		if record.Title != "" {
+			titleScript.Elements = append(titleScript.Elements, scriptElem("title", record.Title))
+		}
+		if record.Location != "" && record.StatusCode >= 300 && record.StatusCode < 400 {
+			titleScript.Elements = append(titleScript.Elements, scriptElem("redirect_url", record.Location))
+			if titleScript.Output == "" {
+				titleScript.Output = "Did not follow redirect to " + record.Location
+			}
+		}
+		if len(titleScript.Elements) > 0 {
+			if _, ok := service.Script.Get("http-title"); !ok {
+				service.Script = append(service.Script, titleScript)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return hosts.handleAll(handle)
+}
+
+// scriptElem creates an <elem> of structured script output.
+func scriptElem(key string, text string) NmapScriptElement {
+	return NmapScriptElement{XMLName: xml.Name{Local: "elem"}, Key: key, Text: text}
+}
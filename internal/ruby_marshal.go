@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"sort"
+)
+
+// Metasploit stores serialized columns (e.g. notes.data) as base64 encoded
+// Ruby Marshal dumps. This is a minimal encoder for the subset of types we
+// need: nil, booleans, small integers, UTF-8 strings, arrays and hashes with
+// string keys.
+// Format reference: https://docs.ruby-lang.org/en/master/marshal_rdoc.html
+
+const (
+	rubyMarshalMajor = 4
+	rubyMarshalMinor = 8
+
+	rubyFixnumMin = -(1 << 30)
+	rubyFixnumMax = 1<<30 - 1
+)
+
+type rubyMarshaler struct {
+	buf     bytes.Buffer
+	symbols map[string]int
+}
+
+func marshalRuby(value interface{}) ([]byte, error) {
+	m := rubyMarshaler{symbols: make(map[string]int)}
+
+	m.buf.WriteByte(rubyMarshalMajor)
+	m.buf.WriteByte(rubyMarshalMinor)
+
+	err := m.writeValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.buf.Bytes(), nil
+}
+
+// encodeRubyBase64 returns the value in the format of Metasploit's
+// MetasploitDataModels::Base64Serializer.
+func encodeRubyBase64(value interface{}) (string, error) {
+	data, err := marshalRuby(value)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func (m *rubyMarshaler) writeValue(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		m.buf.WriteByte('0')
+	case bool:
+		if v {
+			m.buf.WriteByte('T')
+		} else {
+			m.buf.WriteByte('F')
+		}
+	case int:
+		if v < rubyFixnumMin || v > rubyFixnumMax {
+			return fmt.Errorf("integer %d out of Fixnum range", v)
+		}
+		m.buf.WriteByte('i')
+		m.writeInt(v)
+	case string:
+		m.writeString(v)
+	case []string:
+		m.buf.WriteByte('[')
+		m.writeInt(len(v))
+		for _, item := range v {
+			m.writeString(item)
+		}
+	case []interface{}:
+		m.buf.WriteByte('[')
+		m.writeInt(len(v))
+		for _, item := range v {
+			err := m.writeValue(item)
+			if err != nil {
+				return err
+			}
+		}
+	case []map[string]interface{}:
+		m.buf.WriteByte('[')
+		m.writeInt(len(v))
+		for _, item := range v {
+			err := m.writeValue(item)
+			if err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		m.buf.WriteByte('{')
+		m.writeInt(len(keys))
+		for _, key := range keys {
+			m.writeString(key)
+			err := m.writeValue(v[key])
+			if err != nil {
+				return fmt.Errorf("key %q: %w", key, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type %T", value)
+	}
+
+	return nil
+}
+
+// writeInt writes a "long" as used for Fixnums and lengths.
+func (m *rubyMarshaler) writeInt(n int) {
+	switch {
+	case n == 0:
+		m.buf.WriteByte(0)
+		return
+	case 0 < n && n < 123:
+		m.buf.WriteByte(byte(n + 5))
+		return
+	case -124 < n && n < 0:
+		m.buf.WriteByte(byte(int8(n - 5)))
+		return
+	}
+
+	var data [4]byte
+	x := n
+	for i := 1; i <= len(data); i++ {
+		data[i-1] = byte(x & 0xff)
+		x >>= 8
+
+		if x == 0 {
+			m.buf.WriteByte(byte(i))
+			m.buf.Write(data[:i])
+			return
+		}
+		if x == -1 {
+			m.buf.WriteByte(byte(int8(-i)))
+			m.buf.Write(data[:i])
+			return
+		}
+	}
+}
+
+func (m *rubyMarshaler) writeBytes(b []byte) {
+	m.writeInt(len(b))
+	m.buf.Write(b)
+}
+
+func (m *rubyMarshaler) writeSymbol(symbol string) {
+	if index, ok := m.symbols[symbol]; ok {
+		m.buf.WriteByte(';')
+		m.writeInt(index)
+		return
+	}
+
+	m.symbols[symbol] = len(m.symbols)
+	m.buf.WriteByte(':')
+	m.writeBytes([]byte(symbol))
+}
+
+// writeString writes a String with UTF-8 encoding, i.e. with the instance
+// variable E set to true.
+func (m *rubyMarshaler) writeString(s string) {
+	m.buf.WriteByte('I')
+	m.buf.WriteByte('"')
+	m.writeBytes([]byte(s))
+	m.writeInt(1)
+	m.writeSymbol("E")
+	m.buf.WriteByte('T')
+}
@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMarshalRuby(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    interface{}
+		expected []byte
+	}{
+		{"nil", nil, []byte("\x04\x080")},
+		{"small int", 42, []byte("\x04\x08i\x2f")},
+		{"large int", 443, []byte("\x04\x08i\x02\xbb\x01")},
+		{"negative int", -200, []byte("\x04\x08i\xff\x38")},
+		{"string", "hi", []byte("\x04\x08I\"\x07hi\x06:\x06ET")},
+		{
+			"hash",
+			map[string]interface{}{"output": "hi"},
+			[]byte("\x04\x08{\x06I\"\x0boutput\x06:\x06ETI\"\x07hi\x06;\x00T"),
+		},
+		{"array", []interface{}{true, false}, []byte("\x04\x08[\x07TF")},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := marshalRuby(c.value)
+			if err != nil {
+				t.Fatalf("Error marshaling %v: %v", c.value, err)
+			}
+
+			if !bytes.Equal(data, c.expected) {
+				t.Errorf("Marshaling %v: got %q, expected %q", c.value, data, c.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// rustscanLineRegexp matches a line of RustScan's greppable output (-g),
+// e.g. "10.0.0.5 -> [22,80,443]".
+var rustscanLineRegexp = regexp.MustCompile(`^(\S+) -> \[([\d,\s]*)\]$`)
+
+func sniffRustscan(prefix []byte) bool {
+	line, _, _ := strings.Cut(string(prefix), "\n")
+	return rustscanLineRegexp.MatchString(strings.TrimSpace(line))
+}
+
+// ParseRustscan reads RustScan's greppable output (-g), which lists the open
+// TCP ports of each host.
+func ParseRustscan(reader io.Reader, handle HandleHostFunc) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	hosts := newMergedHosts()
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		match := rustscanLineRegexp.FindStringSubmatch(line)
+		if match == nil {
+			if line != "" {
+				log.Debugf("Ignoring unknown line in RustScan output: %q", line)
+			}
+			continue
+		}
+
+		if net.ParseIP(match[1]) == nil {
+			log.Warnf("Ignoring RustScan result of %q, which is not an IP address.", match[1])
+			continue
+		}
+
+		host := hosts.host(match[1])
+		for _, field := range strings.Split(match[2], ",") {
+			port, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				continue
+			}
+			hosts.port(host, "tcp", port)
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return fmt.Errorf("reading line: %w", err)
+	}
+
+	return hosts.handleAll(handle)
+}